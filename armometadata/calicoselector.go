@@ -0,0 +1,444 @@
+package armometadata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CalicoOperator is the operator of a Calico label expression
+type CalicoOperator string
+
+const (
+	CalicoOpHas        CalicoOperator = "has"
+	CalicoOpEqual      CalicoOperator = "=="
+	CalicoOpNotEqual   CalicoOperator = "!="
+	CalicoOpIn         CalicoOperator = "in"
+	CalicoOpNotIn      CalicoOperator = "not in"
+	CalicoOpStartsWith CalicoOperator = "starts with"
+	CalicoOpEndsWith   CalicoOperator = "ends with"
+	CalicoOpContains   CalicoOperator = "contains"
+)
+
+// CalicoExpr is a node of a Calico selector AST
+type CalicoExpr interface {
+	// Evaluate returns true if the labels are selected
+	Evaluate(labels map[string]string) bool
+	String() string
+}
+
+// CalicoAll is the all() expression, it selects everything
+type CalicoAll struct{}
+
+// CalicoGlobal is the global() expression, it selects non-namespaced resources only
+type CalicoGlobal struct{}
+
+// CalicoNot negates an expression
+type CalicoNot struct {
+	Expr CalicoExpr
+}
+
+// CalicoAnd is true if all the expressions are true
+type CalicoAnd struct {
+	Exprs []CalicoExpr
+}
+
+// CalicoOr is true if one of the expressions is true
+type CalicoOr struct {
+	Exprs []CalicoExpr
+}
+
+// CalicoLabelExpr compares a label, Values has a single value except for the in and not in operators
+type CalicoLabelExpr struct {
+	Label    string
+	Operator CalicoOperator
+	Values   []string
+}
+
+// CalicoSelector is a parsed Calico selector
+type CalicoSelector struct {
+	Root CalicoExpr
+}
+
+// ParseCalicoSelectorExpression parses a Calico selector, an empty selector selects everything
+func ParseCalicoSelectorExpression(selector string) (*CalicoSelector, error) {
+	p := &calicoParser{input: selector}
+	p.skipSpaces()
+	if p.done() {
+		return &CalicoSelector{Root: CalicoAll{}}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return &CalicoSelector{Root: root}, nil
+}
+
+// Evaluate returns true if the labels are selected
+func (s *CalicoSelector) Evaluate(labels map[string]string) bool {
+	return s.Root.Evaluate(labels)
+}
+
+func (s *CalicoSelector) String() string {
+	return s.Root.String()
+}
+
+// MatchLabels returns the label equalities required by the selector, e.g. "a == 'b' && has(c)" returns {"a": "b"}.
+// It approximates the selector as matchLabels, disjunctions are not represented.
+func (s *CalicoSelector) MatchLabels() map[string]string {
+	matchLabels := map[string]string{}
+	exprs := []CalicoExpr{s.Root}
+	if and, ok := s.Root.(CalicoAnd); ok {
+		exprs = and.Exprs
+	}
+	for _, expr := range exprs {
+		if e, ok := expr.(CalicoLabelExpr); ok && (e.Operator == CalicoOpEqual || e.Operator == CalicoOpIn && len(e.Values) == 1) {
+			matchLabels[e.Label] = e.Values[0]
+		}
+	}
+	return matchLabels
+}
+
+func (CalicoAll) Evaluate(map[string]string) bool { return true }
+
+func (CalicoAll) String() string { return "all()" }
+
+func (CalicoGlobal) Evaluate(map[string]string) bool { return false }
+
+func (CalicoGlobal) String() string { return "global()" }
+
+func (e CalicoNot) Evaluate(labels map[string]string) bool { return !e.Expr.Evaluate(labels) }
+
+func (e CalicoNot) String() string {
+	switch expr := e.Expr.(type) {
+	case CalicoAll, CalicoGlobal, CalicoNot:
+		return "!" + expr.String()
+	case CalicoLabelExpr:
+		if expr.Operator == CalicoOpHas {
+			return "!" + expr.String()
+		}
+	}
+	return "!(" + e.Expr.String() + ")"
+}
+
+func (e CalicoAnd) Evaluate(labels map[string]string) bool {
+	for _, expr := range e.Exprs {
+		if !expr.Evaluate(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e CalicoAnd) String() string {
+	exprs := make([]string, 0, len(e.Exprs))
+	for _, expr := range e.Exprs {
+		if _, ok := expr.(CalicoOr); ok {
+			exprs = append(exprs, "("+expr.String()+")")
+		} else {
+			exprs = append(exprs, expr.String())
+		}
+	}
+	return strings.Join(exprs, " && ")
+}
+
+func (e CalicoOr) Evaluate(labels map[string]string) bool {
+	for _, expr := range e.Exprs {
+		if expr.Evaluate(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e CalicoOr) String() string {
+	exprs := make([]string, 0, len(e.Exprs))
+	for _, expr := range e.Exprs {
+		exprs = append(exprs, expr.String())
+	}
+	return strings.Join(exprs, " || ")
+}
+
+func (e CalicoLabelExpr) Evaluate(labels map[string]string) bool {
+	v, ok := labels[e.Label]
+	switch e.Operator {
+	case CalicoOpHas:
+		return ok
+	case CalicoOpEqual:
+		return ok && v == e.Values[0]
+	case CalicoOpNotEqual:
+		return !ok || v != e.Values[0]
+	case CalicoOpIn:
+		return ok && slices.Contains(e.Values, v)
+	case CalicoOpNotIn:
+		return !ok || !slices.Contains(e.Values, v)
+	case CalicoOpStartsWith:
+		return ok && strings.HasPrefix(v, e.Values[0])
+	case CalicoOpEndsWith:
+		return ok && strings.HasSuffix(v, e.Values[0])
+	case CalicoOpContains:
+		return ok && strings.Contains(v, e.Values[0])
+	}
+	return false
+}
+
+func (e CalicoLabelExpr) String() string {
+	switch e.Operator {
+	case CalicoOpHas:
+		return "has(" + e.Label + ")"
+	case CalicoOpIn, CalicoOpNotIn:
+		values := make([]string, 0, len(e.Values))
+		for _, v := range e.Values {
+			values = append(values, quoteCalicoValue(v))
+		}
+		return e.Label + " " + string(e.Operator) + " {" + strings.Join(values, ", ") + "}"
+	}
+	return e.Label + " " + string(e.Operator) + " " + quoteCalicoValue(e.Values[0])
+}
+
+func quoteCalicoValue(v string) string {
+	if strings.Contains(v, "'") {
+		return `"` + v + `"`
+	}
+	return "'" + v + "'"
+}
+
+// calicoParser is a recursive descent parser of the Calico selector grammar
+type calicoParser struct {
+	input string
+	pos   int
+}
+
+func (p *calicoParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid calico selector %q at position %d: %s", p.input, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *calicoParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *calicoParser) skipSpaces() {
+	for !p.done() && strings.ContainsRune(" \t\r\n", rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the spaces and the token if present
+func (p *calicoParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// consumeWord is like consume but the token must not be followed by a label character
+func (p *calicoParser) consumeWord(word string) bool {
+	start := p.pos
+	if !p.consume(word) {
+		return false
+	}
+	if !p.done() && isCalicoLabelChar(p.input[p.pos]) {
+		p.pos = start
+		return false
+	}
+	return true
+}
+
+func (p *calicoParser) expect(token string) error {
+	if !p.consume(token) {
+		return p.errorf("expected %q", token)
+	}
+	return nil
+}
+
+func (p *calicoParser) parseOr() (CalicoExpr, error) {
+	exprs := make([]CalicoExpr, 0, 1)
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return CalicoOr{Exprs: exprs}, nil
+}
+
+func (p *calicoParser) parseAnd() (CalicoExpr, error) {
+	exprs := make([]CalicoExpr, 0, 1)
+	for {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return CalicoAnd{Exprs: exprs}, nil
+}
+
+func (p *calicoParser) parseUnary() (CalicoExpr, error) {
+	if p.consume("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return CalicoNot{Expr: expr}, nil
+	}
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	for _, function := range []struct {
+		name string
+		expr CalicoExpr
+	}{{"all", CalicoAll{}}, {"global", CalicoGlobal{}}} {
+		if p.consumeWord(function.name) {
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return function.expr, nil
+		}
+	}
+	if p.consumeWord("has") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		label, err := p.parseLabel()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return CalicoLabelExpr{Label: label, Operator: CalicoOpHas}, nil
+	}
+	return p.parseLabelExpr()
+}
+
+func (p *calicoParser) parseLabelExpr() (CalicoExpr, error) {
+	label, err := p.parseLabel()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []CalicoOperator{CalicoOpEqual, CalicoOpNotEqual, CalicoOpStartsWith, CalicoOpEndsWith, CalicoOpContains} {
+		if p.consumeOperator(op) {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			return CalicoLabelExpr{Label: label, Operator: op, Values: []string{value}}, nil
+		}
+	}
+	for _, op := range []CalicoOperator{CalicoOpIn, CalicoOpNotIn} {
+		if p.consumeOperator(op) {
+			values, err := p.parseValueSet()
+			if err != nil {
+				return nil, err
+			}
+			return CalicoLabelExpr{Label: label, Operator: op, Values: values}, nil
+		}
+	}
+	p.skipSpaces()
+	return nil, p.errorf("expected an operator after label %q", label)
+}
+
+// consumeOperator consumes an operator, the words of multi words operators may be separated by several spaces
+func (p *calicoParser) consumeOperator(op CalicoOperator) bool {
+	start := p.pos
+	for _, word := range strings.Fields(string(op)) {
+		var ok bool
+		if isCalicoLabelChar(word[0]) {
+			ok = p.consumeWord(word)
+		} else {
+			ok = p.consume(word)
+		}
+		if !ok {
+			p.pos = start
+			return false
+		}
+	}
+	return true
+}
+
+func (p *calicoParser) parseLabel() (string, error) {
+	p.skipSpaces()
+	start := p.pos
+	for !p.done() && isCalicoLabelChar(p.input[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.done() {
+			return "", p.errorf("unexpected end of selector, expected a label")
+		}
+		return "", p.errorf("expected a label")
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *calicoParser) parseValue() (string, error) {
+	p.skipSpaces()
+	if p.done() || (p.input[p.pos] != '\'' && p.input[p.pos] != '"') {
+		return "", p.errorf("expected a quoted value")
+	}
+	quote := p.input[p.pos]
+	end := strings.IndexByte(p.input[p.pos+1:], quote)
+	if end < 0 {
+		return "", p.errorf("unterminated value")
+	}
+	value := p.input[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return value, nil
+}
+
+func (p *calicoParser) parseValueSet() ([]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	if p.consume("}") {
+		return values, nil
+	}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.consume("}") {
+			return values, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isCalicoLabelChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_./-", c) >= 0
+}
@@ -0,0 +1,109 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCalicoSelectorExpression(t *testing.T) {
+	labels := map[string]string{"app": "web", "tier": "frontend", "env": "prod-eu"}
+	tests := []struct {
+		selector string
+		want     bool
+		str      string
+	}{
+		{selector: "", want: true, str: "all()"},
+		{selector: "all()", want: true, str: "all()"},
+		{selector: "global()", want: false, str: "global()"},
+		{selector: "app == 'web'", want: true, str: "app == 'web'"},
+		{selector: `app == "db"`, want: false, str: "app == 'db'"},
+		{selector: "app != 'db'", want: true, str: "app != 'db'"},
+		{selector: "missing != 'db'", want: true, str: "missing != 'db'"},
+		{selector: "has(tier)", want: true, str: "has(tier)"},
+		{selector: "!has(tier)", want: false, str: "!has(tier)"},
+		{selector: "! has( missing )", want: true, str: "!has(missing)"},
+		{selector: "tier in {'frontend', 'backend'}", want: true, str: "tier in {'frontend', 'backend'}"},
+		{selector: "tier not  in {'frontend'}", want: false, str: "tier not in {'frontend'}"},
+		{selector: "missing not in {}", want: true, str: "missing not in {}"},
+		{selector: "env starts with 'prod'", want: true, str: "env starts with 'prod'"},
+		{selector: "env ends with 'us'", want: false, str: "env ends with 'us'"},
+		{selector: "env contains 'd-e'", want: true, str: "env contains 'd-e'"},
+		{selector: "app == 'db' || tier == 'frontend' && has(env)", want: true, str: "app == 'db' || tier == 'frontend' && has(env)"},
+		{selector: "(app == 'db' || tier == 'frontend') && !has(env)", want: false, str: "(app == 'db' || tier == 'frontend') && !has(env)"},
+		{selector: "!(app == 'web' && has(tier))", want: false, str: "!(app == 'web' && has(tier))"},
+		{selector: "projectcalico.org/namespace == 'default'", want: false, str: "projectcalico.org/namespace == 'default'"},
+		{selector: "allowed == 'web'", want: false, str: "allowed == 'web'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := ParseCalicoSelectorExpression(tc.selector)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, selector.Evaluate(labels))
+			assert.Equal(t, tc.str, selector.String())
+
+			// the serialized selector parses to the same AST
+			reparsed, err := ParseCalicoSelectorExpression(selector.String())
+			assert.NoError(t, err)
+			assert.Equal(t, selector, reparsed)
+		})
+	}
+}
+
+func TestParseCalicoSelectorExpressionErrors(t *testing.T) {
+	for _, selector := range []string{
+		"app ==",
+		"app == web",
+		"app == 'web",
+		"app = 'web'",
+		"(app == 'web'",
+		"app == 'web')",
+		"app == 'web' &&",
+		"has(app",
+		"has()",
+		"all(",
+		"tier in {'a' 'b'}",
+		"tier in 'a'",
+		"&& app == 'web'",
+	} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := ParseCalicoSelectorExpression(selector)
+			assert.Error(t, err)
+		})
+	}
+	_, err := ParseCalicoSelectorExpression("app == 'web' && tier")
+	assert.EqualError(t, err, `invalid calico selector "app == 'web' && tier" at position 20: expected an operator after label "tier"`)
+}
+
+func TestCalicoSelectorMatchLabels(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     map[string]string
+	}{
+		{selector: "role == 'database' && has(tier) && env in {'prod'}", want: map[string]string{"role": "database", "env": "prod"}},
+		{selector: "role == 'database' || role == 'cache'", want: map[string]string{}},
+		{selector: "role != 'database'", want: map[string]string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := ParseCalicoSelectorExpression(tc.selector)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, selector.MatchLabels())
+			assert.Equal(t, tc.want, ParseCalicoSelector([]byte(`"`+tc.selector+`"`)))
+		})
+	}
+}
+
+func TestCalicoPolicySelectsWorkload(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"projectcalico.org/v3","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"selector":"role in {'db', 'cache'} && !has(canary) && projectcalico.org/serviceaccount == 'db'"}}`)
+	assert.NotNil(t, policy.CalicoSelector)
+	assert.Equal(t, map[string]string{"projectcalico.org/serviceaccount": "db"}, policy.NetworkPolicyPodSelectorMatchLabels)
+
+	cache := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"role":"cache"}},"spec":{"serviceAccountName":"db"}}`)
+	canary := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"role":"cache","canary":"true"}},"spec":{"serviceAccountName":"db"}}`)
+	web := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"role":"web"}},"spec":{"serviceAccountName":"db"}}`)
+	assert.True(t, policy.SelectsWorkload(cache))
+	assert.False(t, policy.SelectsWorkload(canary))
+	assert.False(t, policy.SelectsWorkload(web))
+}
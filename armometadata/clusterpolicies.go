@@ -0,0 +1,142 @@
+package armometadata
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/ptr"
+)
+
+// PolicyAPIGroup is the api group of the AdminNetworkPolicy kinds
+const PolicyAPIGroup = "policy.networking.k8s.io"
+
+// AdminNetworkPolicy kinds
+const (
+	KindAdminNetworkPolicy         = "AdminNetworkPolicy"
+	KindBaselineAdminNetworkPolicy = "BaselineAdminNetworkPolicy"
+)
+
+// NamespaceNameLabel is the label set by Kubernetes on each namespace to its name
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// CalicoNamespaceNameLabel is the virtual label of the namespace name used by Calico namespace selectors
+const CalicoNamespaceNameLabel = "projectcalico.org/name"
+
+// adminNetworkPolicyExtractors extract the priority, subject and rules of AdminNetworkPolicies and BaselineAdminNetworkPolicies.
+// The subject is either all the pods of the selected namespaces, or the selected pods of the selected namespaces.
+var adminNetworkPolicyExtractors = []Extractor{
+	{Paths: []string{"spec.priority"}, APIGroup: PolicyAPIGroup, Extract: func(m *Metadata, _ string, _, value []byte) {
+		if priority, err := strconv.ParseInt(unquote(value), 10, 32); err == nil {
+			m.PolicyPriority = ptr.To(int32(priority))
+		}
+	}},
+	{Paths: slices.Concat(selectorPaths("spec.subject.namespaces"), selectorPaths("spec.subject.pods.namespaceSelector")), APIGroup: PolicyAPIGroup,
+		Extract: func(m *Metadata, p string, key, value []byte) {
+			parseSelectorPath(&m.NetworkPolicyNamespaceSelector, p, key, unquote(value))
+		}},
+	{Paths: selectorPaths("spec.subject.pods.podSelector"), APIGroup: PolicyAPIGroup, Extract: func(m *Metadata, p string, key, value []byte) {
+		parseSelectorPath(&m.NetworkPolicyPodSelector, p, key, unquote(value))
+	}},
+	{Paths: []string{"spec.ingress."}, APIGroup: PolicyAPIGroup, Extract: func(m *Metadata, p string, key, value []byte) {
+		setHasIngress(m)
+		parseAdminNetworkPolicyRules(m, DirectionIngress, p, key, unquote(value))
+	}},
+	{Paths: []string{"spec.egress."}, APIGroup: PolicyAPIGroup, Extract: func(m *Metadata, p string, key, value []byte) {
+		setHasEgress(m)
+		parseAdminNetworkPolicyRules(m, DirectionEgress, p, key, unquote(value))
+	}},
+}
+
+// parseAdminNetworkPolicyRules fills the ingress or egress rules, p is the path within the rules list
+func parseAdminNetworkPolicyRules(m *Metadata, direction, p string, key []byte, v string) {
+	if p == "" {
+		if v == "{" {
+			m.NetworkPolicyRules = append(m.NetworkPolicyRules, NetworkPolicyRule{Direction: direction})
+		}
+		return
+	}
+	rule := m.lastRule()
+	if rule == nil {
+		return
+	}
+	switch p {
+	case ".action":
+		rule.Action = v
+		return
+	case ".from.", ".to.":
+		if v == "{" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{})
+		}
+		return
+	case ".ports.":
+		if v == "{" {
+			rule.Ports = append(rule.Ports, NetworkPolicyPort{})
+		}
+		return
+	}
+
+	if portPath, ok := strings.CutPrefix(p, ".ports.."); ok {
+		if port := rule.lastPort(); port != nil {
+			parseAdminNetworkPolicyPort(port, portPath, v)
+		}
+		return
+	}
+	peerPath, ok := cutAny(p, ".from..", ".to..")
+	peer := rule.lastPeer()
+	if !ok || peer == nil {
+		return
+	}
+	if selectorPath, ok := cutSelectorPath(peerPath, "namespaces"); ok {
+		parseSelectorPath(&peer.NamespaceSelector, selectorPath, key, v)
+	} else if selectorPath, ok := cutSelectorPath(peerPath, "pods.namespaceSelector"); ok {
+		parseSelectorPath(&peer.NamespaceSelector, selectorPath, key, v)
+	} else if selectorPath, ok := cutSelectorPath(peerPath, "pods.podSelector"); ok {
+		parseSelectorPath(&peer.PodSelector, selectorPath, key, v)
+	} else if peerPath == "networks." {
+		peer.IPBlocks = append(peer.IPBlocks, IPBlock{CIDR: v})
+	}
+}
+
+// parseAdminNetworkPolicyPort fills a port number, a named port or a port range, p is the path within the port
+func parseAdminNetworkPolicyPort(port *NetworkPolicyPort, p, v string) {
+	switch p {
+	case "portNumber.protocol", "portRange.protocol":
+		port.Protocol = v
+	case "portNumber.port", "portRange.start", "namedPort":
+		port.Port = v
+	case "portRange.end":
+		if end, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.EndPort = int32(end)
+		}
+	}
+}
+
+// isolatesByDefault returns true if the AdminNetworkPolicy denies the traffic of the direction from all namespaces,
+// as a default deny of BaselineAdminNetworkPolicies
+func (m *Metadata) isolatesByDefault(direction string) bool {
+	for _, rule := range m.NetworkPolicyRules {
+		if rule.Direction != direction || rule.Action != RuleActionDeny || len(rule.Ports) > 0 {
+			continue
+		}
+		for _, peer := range rule.Peers {
+			if peer.PodSelector == nil && peer.NamespaceSelector != nil &&
+				len(peer.NamespaceSelector.MatchLabels) == 0 && len(peer.NamespaceSelector.MatchExpressions) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withNamespaceNameLabels returns the labels of the namespace with the name labels set by Kubernetes and Calico
+func withNamespaceNameLabels(namespace string, labels map[string]string) map[string]string {
+	all := map[string]string{
+		NamespaceNameLabel:       namespace,
+		CalicoNamespaceNameLabel: namespace,
+	}
+	for k, v := range labels {
+		all[k] = v
+	}
+	return all
+}
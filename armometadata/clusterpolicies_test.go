@@ -0,0 +1,133 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestExtractAdminNetworkPolicy(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"AdminNetworkPolicy","metadata":{"name":"cluster-control"},
+		"spec":{"priority":10,
+			"subject":{"pods":{"namespaceSelector":{"matchLabels":{"env":"prod"}},"podSelector":{"matchLabels":{"app":"api"}}}},
+			"ingress":[{"name":"deny-all","action":"Deny","from":[{"namespaces":{}}]}],
+			"egress":[{"action":"Pass","to":[{"pods":{"namespaceSelector":{"matchLabels":{"kubernetes.io/metadata.name":"dns"}},"podSelector":{}}},{"networks":["10.0.0.0/8"]}],
+				"ports":[{"portNumber":{"protocol":"UDP","port":53}},{"namedPort":"metrics"},{"portRange":{"protocol":"TCP","start":8080,"end":8090}}]}]}}`)
+	assert.True(t, policy.IsNetworkPolicy())
+	assert.True(t, policy.IsClusterWideNetworkPolicy())
+	assert.Equal(t, ptr.To(int32(10)), policy.PolicyPriority)
+	assert.Equal(t, &LabelSelector{MatchLabels: map[string]string{"env": "prod"}}, policy.NetworkPolicyNamespaceSelector)
+	assert.Equal(t, &LabelSelector{MatchLabels: map[string]string{"app": "api"}}, policy.NetworkPolicyPodSelector)
+	assert.Equal(t, []NetworkPolicyRule{
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionDeny,
+			Peers:     []NetworkPolicyPeer{{NamespaceSelector: &LabelSelector{}}},
+		},
+		{
+			Direction: DirectionEgress,
+			Action:    RuleActionPass,
+			Peers: []NetworkPolicyPeer{
+				{NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "dns"}}, PodSelector: &LabelSelector{}},
+				{IPBlocks: []IPBlock{{CIDR: "10.0.0.0/8"}}},
+			},
+			Ports: []NetworkPolicyPort{{Protocol: "UDP", Port: "53"}, {Port: "metrics"}, {Protocol: "TCP", Port: "8080", EndPort: 8090}},
+		},
+	}, policy.NetworkPolicyRules)
+	assert.True(t, policy.IsolatesIngress())
+	assert.False(t, policy.IsolatesEgress())
+}
+
+func TestExtractClusterWidePolicySubjects(t *testing.T) {
+	ccnp := extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumClusterwideNetworkPolicy","metadata":{"name":"host"},
+		"spec":{"nodeSelector":{"matchLabels":{"node-role.kubernetes.io/control-plane":""}},"ingress":[{"fromEntities":["cluster"]}]}}`)
+	assert.True(t, ccnp.IsClusterWideNetworkPolicy())
+	assert.Equal(t, &LabelSelector{MatchLabels: map[string]string{"node-role.kubernetes.io/control-plane": ""}}, ccnp.NodeSelector)
+	assert.Nil(t, ccnp.NetworkPolicyPodSelector)
+
+	gnp := extractTestMetadata(t, `{"apiVersion":"projectcalico.org/v3","kind":"GlobalNetworkPolicy","metadata":{"name":"security.deny-external"},
+		"spec":{"tier":"security","order":100.5,"namespaceSelector":"env == 'prod'","selector":"app == 'api'","types":["Ingress"]}}`)
+	assert.True(t, gnp.IsClusterWideNetworkPolicy())
+	assert.Equal(t, "security", gnp.PolicyTier)
+	assert.Equal(t, ptr.To(100.5), gnp.PolicyOrder)
+	assert.Equal(t, "env == 'prod'", gnp.CalicoNamespaceSelector.String())
+}
+
+func TestSelectsWorkloadInNamespace(t *testing.T) {
+	deployment := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"api","namespace":"production"},
+		"spec":{"template":{"metadata":{"labels":{"app":"api"}},"spec":{"containers":[{"name":"api"}]}}}}`)
+	prod := map[string]string{"env": "prod"}
+	tests := []struct {
+		name          string
+		policy        string
+		selects       bool
+		selectsInProd bool // with the prod namespace labels
+	}{
+		{
+			name: "admin network policy namespaces",
+			policy: `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"AdminNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"priority":1,"subject":{"namespaces":{"matchLabels":{"env":"prod"}}}}}`,
+			selectsInProd: true,
+		},
+		{
+			name: "admin network policy namespace name",
+			policy: `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"AdminNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"priority":1,"subject":{"namespaces":{"matchExpressions":[{"key":"kubernetes.io/metadata.name","operator":"In","values":["production"]}]}}}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "admin network policy pods",
+			policy: `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"AdminNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"priority":1,"subject":{"pods":{"namespaceSelector":{},"podSelector":{"matchLabels":{"app":"web"}}}}}}`,
+		},
+		{
+			name: "baseline admin network policy",
+			policy: `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"BaselineAdminNetworkPolicy","metadata":{"name":"default"},
+				"spec":{"subject":{"pods":{"namespaceSelector":{},"podSelector":{"matchLabels":{"app":"api"}}}}}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "calico global network policy",
+			policy: `{"apiVersion":"projectcalico.org/v3","kind":"GlobalNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"namespaceSelector":"env == 'prod'","selector":"app == 'api'"}}`,
+			selectsInProd: true,
+		},
+		{
+			name: "calico global network policy namespace name",
+			policy: `{"apiVersion":"projectcalico.org/v3","kind":"GlobalNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"namespaceSelector":"projectcalico.org/name == 'production'"}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "cilium host policy",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumClusterwideNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"nodeSelector":{},"ingress":[{}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := extractTestMetadata(t, tt.policy)
+			assert.Equal(t, tt.selects, policy.SelectsWorkload(deployment))
+			assert.Equal(t, tt.selectsInProd, policy.SelectsWorkloadInNamespace(deployment, prod))
+		})
+	}
+}
+
+func TestAdminNetworkPolicyIsolation(t *testing.T) {
+	deployment := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"api","namespace":"production"},
+		"spec":{"template":{"metadata":{"labels":{"app":"api"}},"spec":{"containers":[{"name":"api"}]}}}}`)
+	policies := []*Metadata{
+		extractTestMetadata(t, `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"AdminNetworkPolicy","metadata":{"name":"allow-monitoring"},
+			"spec":{"priority":5,"subject":{"namespaces":{}},"ingress":[{"action":"Allow","from":[{"namespaces":{"matchLabels":{"team":"monitoring"}}}]}]}}`),
+		extractTestMetadata(t, `{"apiVersion":"policy.networking.k8s.io/v1alpha1","kind":"BaselineAdminNetworkPolicy","metadata":{"name":"default"},
+			"spec":{"subject":{"namespaces":{}},"egress":[{"action":"Deny","to":[{"namespaces":{"matchExpressions":[]}}]}]}}`),
+	}
+	match := MatchNetworkPoliciesInNamespace(deployment, map[string]string{"team": "api"}, policies)
+	assert.Equal(t, []string{"AdminNetworkPolicy/allow-monitoring", "BaselineAdminNetworkPolicy/default"}, policyNames(match.Policies))
+	assert.False(t, match.IngressIsolated)
+	assert.True(t, match.EgressIsolated)
+}
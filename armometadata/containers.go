@@ -0,0 +1,181 @@
+package armometadata
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/armosec/utils-k8s-go/wlid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// podSpecPrefixes are the json paths of the pod specs of Pods, pod templates and CronJob job templates
+var podSpecPrefixes = []string{"spec.template.spec.", "spec.jobTemplate.spec.template.spec.", "spec."}
+
+// containerListKeys maps the pod spec container lists to the container types
+var containerListKeys = []struct {
+	key           string
+	containerType wlid.ContainerType
+}{
+	{"initContainers.", wlid.InitContainer},
+	{"containers.", wlid.Container},
+	{"ephemeralContainers.", wlid.EphemeralContainer},
+}
+
+// ContainerInfo holds the fields of a container of a pod spec
+type ContainerInfo struct {
+	Type            wlid.ContainerType
+	Name            string
+	Image           string
+	ImagePullPolicy corev1.PullPolicy
+	Ports           []corev1.ContainerPort
+	Requests        corev1.ResourceList
+	Limits          corev1.ResourceList
+	HasCommand      bool // the container overrides the image entrypoint
+	HasArgs         bool // the container overrides the image cmd
+	// SecurityContext is the container security context as declared, see Metadata.EffectiveSecurityContext
+	SecurityContext *corev1.SecurityContext
+}
+
+// GetContainerInfo returns the container of the given type and name, nil if not found
+func (m *Metadata) GetContainerInfo(containerType wlid.ContainerType, name string) *ContainerInfo {
+	for i := range m.ContainerInfos {
+		if m.ContainerInfos[i].Type == containerType && m.ContainerInfos[i].Name == name {
+			return &m.ContainerInfos[i]
+		}
+	}
+	return nil
+}
+
+// Images returns the images of the containers, in the pod spec order and without duplicates
+func (m *Metadata) Images() []string {
+	images := make([]string, 0, len(m.ContainerInfos))
+	seen := map[string]struct{}{}
+	for i := range m.ContainerInfos {
+		image := m.ContainerInfos[i].Image
+		if _, ok := seen[image]; ok || image == "" {
+			continue
+		}
+		seen[image] = struct{}{}
+		images = append(images, image)
+	}
+	return images
+}
+
+// podSpecExtractors extract the containers, host namespaces, security context and references of the pod specs
+func podSpecExtractors() []Extractor {
+	extractors := make([]Extractor, 0, len(podSpecPrefixes)*(len(containerListKeys)+5))
+	for _, prefix := range podSpecPrefixes {
+		for _, list := range containerListKeys {
+			containerType := list.containerType
+			extractors = append(extractors, Extractor{
+				Paths: []string{prefix + list.key},
+				// the path of the container element itself is empty
+				Extract: func(m *Metadata, p string, key, value []byte) {
+					parseContainer(m, containerType, strings.TrimPrefix(p, "."), key, value)
+				},
+			})
+		}
+		extractors = append(extractors,
+			Extractor{Paths: []string{prefix + "hostNetwork", prefix + "hostPID", prefix + "hostIPC"}, Extract: parseHostNamespaces},
+			Extractor{Paths: []string{prefix + "securityContext."}, Extract: func(m *Metadata, p string, _, value []byte) {
+				parsePodSecurityContext(m, p, unquote(value))
+			}},
+			Extractor{Paths: []string{prefix + "serviceAccountName", prefix + "serviceAccount", prefix + "automountServiceAccountToken"},
+				Extract: parseServiceAccountReferences},
+			Extractor{Paths: []string{prefix + "imagePullSecrets."}, Extract: parseImagePullSecrets},
+			Extractor{Paths: []string{prefix + "volumes."}, NewExtract: newPodVolumesExtractor},
+		)
+	}
+	return extractors
+}
+
+// parseContainer fills the container records and names, containers are appended in the pod spec order
+func parseContainer(m *Metadata, containerType wlid.ContainerType, containerPath string, key, value []byte) {
+	v := unquote(value)
+	if containerPath == "" {
+		switch {
+		case v == "{":
+			m.ContainerInfos = append(m.ContainerInfos, ContainerInfo{Type: containerType})
+		case v == "}" && len(m.ContainerInfos) > 0:
+			m.References.setContainer(m.ContainerInfos[len(m.ContainerInfos)-1].Name)
+		}
+		return
+	}
+	if len(m.ContainerInfos) == 0 {
+		return
+	}
+	container := &m.ContainerInfos[len(m.ContainerInfos)-1]
+
+	switch {
+	case containerPath == "name":
+		container.Name = v
+		switch containerType {
+		case wlid.InitContainer:
+			m.InitContainers[v] = struct{}{}
+		case wlid.Container:
+			m.Containers[v] = struct{}{}
+		case wlid.EphemeralContainer:
+			m.EphemeralContainers[v] = struct{}{}
+		}
+	case containerPath == "image":
+		container.Image = v
+	case containerPath == "imagePullPolicy":
+		container.ImagePullPolicy = corev1.PullPolicy(v)
+	case containerPath == "command.":
+		container.HasCommand = true
+	case containerPath == "args.":
+		container.HasArgs = true
+	case containerPath == "ports.":
+		if v == "{" {
+			container.Ports = append(container.Ports, corev1.ContainerPort{})
+		}
+	case strings.HasPrefix(containerPath, "ports.."):
+		if len(container.Ports) > 0 {
+			parseContainerPort(&container.Ports[len(container.Ports)-1], key, v)
+		}
+	case strings.HasPrefix(containerPath, "resources.requests."):
+		container.Requests = addResourceQuantity(container.Requests, key, v)
+	case strings.HasPrefix(containerPath, "resources.limits."):
+		container.Limits = addResourceQuantity(container.Limits, key, v)
+	case strings.HasPrefix(containerPath, "securityContext."):
+		if container.SecurityContext == nil {
+			container.SecurityContext = &corev1.SecurityContext{}
+		}
+		parseContainerSecurityContext(container.SecurityContext, containerPath[len("securityContext."):], v)
+	case strings.HasPrefix(containerPath, "env"):
+		parseContainerReferences(m, containerPath, key, v)
+	}
+}
+
+func parseContainerPort(port *corev1.ContainerPort, key []byte, v string) {
+	switch unquote(key) {
+	case "name":
+		port.Name = v
+	case "containerPort":
+		if p, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.ContainerPort = int32(p)
+		}
+	case "hostPort":
+		if p, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.HostPort = int32(p)
+		}
+	case "hostIP":
+		port.HostIP = v
+	case "protocol":
+		port.Protocol = corev1.Protocol(v)
+	}
+}
+
+// addResourceQuantity adds a quantity to the resource list, invalid quantities are ignored
+func addResourceQuantity(resources corev1.ResourceList, key []byte, v string) corev1.ResourceList {
+	quantity, err := resource.ParseQuantity(v)
+	if err != nil {
+		return resources
+	}
+	if resources == nil {
+		resources = corev1.ResourceList{}
+	}
+	resources[corev1.ResourceName(unquote(key))] = quantity
+	return resources
+}
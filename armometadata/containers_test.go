@@ -0,0 +1,121 @@
+package armometadata
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/armosec/utils-k8s-go/wlid"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestExtractContainerInfos(t *testing.T) {
+	tests := []struct {
+		name string
+		want []ContainerInfo
+	}{
+		{
+			name: "pod",
+			want: []ContainerInfo{
+				{
+					Type:            wlid.Container,
+					Name:            "kubescape",
+					Image:           "quay.io/kubescape/kubescape:v3.0.1",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Ports:           []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+					Requests:        corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("400Mi")},
+					Limits:          corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					HasCommand:      true,
+				},
+			},
+		},
+		{
+			name: "testdeployment",
+			want: []ContainerInfo{
+				{
+					Type:            wlid.InitContainer,
+					Name:            "init-container-1",
+					Image:           "gcr.io/google-samples/microservices-demo/emailservice:v0.5.1",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Requests:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m"), corev1.ResourceMemory: resource.MustParse("32Mi")},
+					Limits:          corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+					HasCommand:      true,
+				},
+				{
+					Type:            wlid.Container,
+					Name:            "server",
+					Image:           "gcr.io/google-samples/microservices-demo/emailservice:v0.5.1",
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Ports:           []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+					Requests:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+					Limits:          corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+			},
+		},
+		{
+			name: "testcronjob",
+			want: []ContainerInfo{
+				{
+					Type:       wlid.Container,
+					Name:       "backup-container",
+					Image:      "backup-image:v1",
+					HasCommand: true,
+				},
+			},
+		},
+		{
+			name: "service",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := os.ReadFile(fmt.Sprintf("testdata/%s.json", tt.name))
+			assert.NoError(t, err)
+			m, err := ExtractMetadataFromJsonBytes(input)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.want), len(m.ContainerInfos))
+			for i := range tt.want {
+				got := m.ContainerInfos[i]
+				want := tt.want[i]
+				assert.Equal(t, want.Type, got.Type)
+				assert.Equal(t, want.Name, got.Name)
+				assert.Equal(t, want.Image, got.Image)
+				assert.Equal(t, want.ImagePullPolicy, got.ImagePullPolicy)
+				assert.Equal(t, want.Ports, got.Ports)
+				assert.Equal(t, want.HasCommand, got.HasCommand)
+				assert.Equal(t, want.HasArgs, got.HasArgs)
+				assertResourceListEqual(t, want.Requests, got.Requests)
+				assertResourceListEqual(t, want.Limits, got.Limits)
+			}
+		})
+	}
+}
+
+func assertResourceListEqual(t *testing.T, want, got corev1.ResourceList) {
+	assert.Equal(t, len(want), len(got))
+	for name, quantity := range want {
+		assert.Zero(t, quantity.Cmp(got[name]), "resource %s", name)
+	}
+}
+
+func TestExtractContainerInfosOrder(t *testing.T) {
+	input := []byte(`{"kind":"Pod","spec":{
+		"containers":[{"name":"b","image":"nginx","args":["-g"],"ports":[{"containerPort":80},{"containerPort":443,"hostPort":8443}]},{"name":"a","image":"nginx","command":[]}],
+		"ephemeralContainers":[{"name":"debug","image":"busybox"}]}}`)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(m.ContainerInfos))
+	assert.Equal(t, "b", m.ContainerInfos[0].Name)
+	assert.True(t, m.ContainerInfos[0].HasArgs)
+	assert.Equal(t, []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 443, HostPort: 8443}}, m.ContainerInfos[0].Ports)
+	assert.Equal(t, "a", m.ContainerInfos[1].Name)
+	assert.False(t, m.ContainerInfos[1].HasCommand)
+	assert.Equal(t, wlid.EphemeralContainer, m.ContainerInfos[2].Type)
+	assert.Equal(t, []string{"nginx", "busybox"}, m.Images())
+	assert.Equal(t, "busybox", m.GetContainerInfo(wlid.EphemeralContainer, "debug").Image)
+	assert.Nil(t, m.GetContainerInfo(wlid.Container, "debug"))
+	assert.Contains(t, m.Containers, "a")
+	assert.Contains(t, m.EphemeralContainers, "debug")
+}
@@ -0,0 +1,303 @@
+package armometadata
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/olvrng/ujson"
+)
+
+// ExtractorFunc extracts a value of the walked object into m.
+// p is the path of the value below the matched prefix, empty for exact paths, e.g. "from..podSelector" for the
+// prefix "spec.ingress." and the json path "spec.ingress..from..podSelector". key and value are the raw json key and value.
+type ExtractorFunc func(m *Metadata, p string, key, value []byte)
+
+// Extractor extracts the values of some json paths of some objects into Metadata
+type Extractor struct {
+	// Paths are the json paths of the extracted values. A path ending with a dot is a prefix matching the paths below it,
+	// other paths match exactly. Array elements are empty path elements, e.g. "spec.ingress..from.".
+	// An extractor without paths matches all the paths of the objects it applies to.
+	Paths []string
+	// APIVersion, APIGroup and Kinds restrict the extractor to some objects, empty for all objects.
+	// They are matched against the top-level apiVersion and kind of the object, wherever they are in the object.
+	APIVersion string
+	APIGroup   string
+	Kinds      []string
+	Extract    ExtractorFunc
+	// NewExtract creates the extract function of each walk, instead of Extract, for the extractors keeping
+	// state between the values of an object. It is called once per walk, on the first extracted value.
+	NewExtract func() ExtractorFunc
+	// Done is called after the walk on the objects the extractor applies to, nil if not needed
+	Done func(m *Metadata)
+}
+
+// appliesTo returns true if the object passes the extractor filters
+func (e *Extractor) appliesTo(m *Metadata) bool {
+	if e.APIVersion != "" && e.APIVersion != m.ApiVersion {
+		return false
+	}
+	if e.APIGroup != "" && e.APIGroup != m.APIGroup() {
+		return false
+	}
+	return len(e.Kinds) == 0 || slices.Contains(e.Kinds, m.Kind)
+}
+
+func (e *Extractor) filtered() bool {
+	return e.APIVersion != "" || e.APIGroup != "" || len(e.Kinds) > 0
+}
+
+// extractorNode is a node of the path trie, the children are keyed by path element
+type extractorNode struct {
+	children map[string]*extractorNode
+	exact    []*Extractor // extractors of the path of the node
+	prefix   []*Extractor // extractors of the paths below the node
+}
+
+// child returns the child of the path element, nil if none. It is safe to call on a nil node.
+func (n *extractorNode) child(element string) *extractorNode {
+	if n == nil {
+		return nil
+	}
+	return n.children[element]
+}
+
+// insertExtractor adds the extractor to the list, the filtered extractors come first and keep the registration order
+func insertExtractor(extractors []*Extractor, e *Extractor) []*Extractor {
+	if !e.filtered() {
+		return append(extractors, e)
+	}
+	i := slices.IndexFunc(extractors, func(other *Extractor) bool { return !other.filtered() })
+	if i < 0 {
+		return append(extractors, e)
+	}
+	return slices.Insert(extractors, i, e)
+}
+
+// firstApplying returns the first extractor of the list applying to the object, nil if none
+func firstApplying(extractors []*Extractor, m *Metadata) *Extractor {
+	for _, e := range extractors {
+		if e.appliesTo(m) {
+			return e
+		}
+	}
+	return nil
+}
+
+// ExtractorRegistry is a concurrency-safe set of extractors, dispatched by a trie of their paths.
+// A value is extracted by a single extractor, the one with the deepest matching path applying to the object.
+// Among the extractors of the same path, the ones restricted to some objects come first, in the registration order.
+type ExtractorRegistry struct {
+	mu         sync.RWMutex
+	root       *extractorNode
+	extractors []*Extractor // in the registration order, for the Done hooks
+}
+
+// DefaultExtractorRegistry is the registry used by ExtractMetadataFromJsonBytes, seeded with BuiltinExtractors
+var DefaultExtractorRegistry = NewExtractorRegistry(BuiltinExtractors()...)
+
+// BuiltinExtractors returns the extractors of the object metadata, workloads, RBAC objects, services, routes and
+// network policies
+func BuiltinExtractors() []Extractor {
+	return slices.Concat(
+		metadataExtractors,
+		workloadExtractors,
+		podSpecExtractors(),
+		rbacExtractors,
+		serviceExtractors,
+		routeExtractors,
+		networkPolicyExtractors,
+		ciliumExtractors,
+		calicoExtractors,
+		istioExtractors,
+		adminNetworkPolicyExtractors,
+	)
+}
+
+// NewExtractorRegistry returns a registry with the given extractors
+func NewExtractorRegistry(extractors ...Extractor) *ExtractorRegistry {
+	r := &ExtractorRegistry{root: &extractorNode{}}
+	r.Register(extractors...)
+	return r
+}
+
+// Register adds extractors to the registry
+func (r *ExtractorRegistry) Register(extractors ...Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, extractor := range extractors {
+		e := &extractor
+		r.extractors = append(r.extractors, e)
+		if e.Extract == nil && e.NewExtract == nil {
+			continue
+		}
+		if len(e.Paths) == 0 {
+			r.root.prefix = insertExtractor(r.root.prefix, e)
+		}
+		for _, path := range e.Paths {
+			r.insert(path, e)
+		}
+	}
+}
+
+// insert must be called with the lock held
+func (r *ExtractorRegistry) insert(path string, e *Extractor) {
+	path, isPrefix := strings.CutSuffix(path, ".")
+	node := r.root
+	for _, element := range strings.Split(path, ".") {
+		child := node.child(element)
+		if child == nil {
+			child = &extractorNode{}
+			if node.children == nil {
+				node.children = map[string]*extractorNode{}
+			}
+			node.children[element] = child
+		}
+		node = child
+	}
+	if isPrefix {
+		node.prefix = insertExtractor(node.prefix, e)
+	} else {
+		node.exact = insertExtractor(node.exact, e)
+	}
+}
+
+// match returns the extractor of the value at the path elements and the path below its prefix.
+// nodes are the trie nodes of the path elements, nil below the deepest node.
+func (r *ExtractorRegistry) match(m *Metadata, elements []string, nodes []*extractorNode) (*Extractor, string) {
+	n := len(elements)
+	if n > 0 && nodes[n-1] != nil {
+		if e := firstApplying(nodes[n-1].exact, m); e != nil {
+			return e, ""
+		}
+	}
+	for depth := n - 1; depth >= 0; depth-- {
+		node := r.root
+		if depth > 0 {
+			node = nodes[depth-1]
+		}
+		if node == nil {
+			continue
+		}
+		if e := firstApplying(node.prefix, m); e != nil {
+			return e, strings.Join(elements[depth:], ".")
+		}
+	}
+	return nil, ""
+}
+
+// Extract extracts metadata from the JSON bytes of a Kubernetes object with the registered extractors
+func (r *ExtractorRegistry) Extract(input []byte) (Metadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// output values
+	m := Metadata{
+		Annotations:                         map[string]string{},
+		Labels:                              map[string]string{},
+		OwnerReferences:                     map[string]string{},
+		SelectorMatchLabels:                 map[string]string{},
+		PodSpecLabels:                       map[string]string{},
+		PodSpecAnnotations:                  map[string]string{},
+		NetworkPolicyPodSelectorMatchLabels: map[string]string{},
+		ServicePodSelectorMatchLabels:       map[string]string{},
+		InitContainers:                      map[string]struct{}{},
+		Containers:                          map[string]struct{}{},
+		EphemeralContainers:                 map[string]struct{}{},
+	}
+
+	// the filters are matched against the object type before the walk
+	m.ApiVersion, m.Kind = objectType(input)
+
+	// ujson parsing, the trie nodes follow the path elements
+	elements := make([]string, 0)
+	nodes := make([]*extractorNode, 0)
+	var walkExtractors map[*Extractor]ExtractorFunc // extract functions created by NewExtract
+	err := ujson.Walk(input, func(level int, key, value []byte) bool {
+		if level == 0 {
+			// the object itself
+			return true
+		}
+		element := unquote(key)
+		parent := r.root
+		if level > 1 {
+			parent = nodes[level-2]
+		}
+		elements = slices.Replace(elements, level-1, len(elements), element)
+		nodes = slices.Replace(nodes, level-1, len(nodes), parent.child(element))
+		e, p := r.match(&m, elements, nodes)
+		if e == nil {
+			return true
+		}
+		extract := e.Extract
+		if e.NewExtract != nil {
+			if extract = walkExtractors[e]; extract == nil {
+				if walkExtractors == nil {
+					walkExtractors = map[*Extractor]ExtractorFunc{}
+				}
+				extract = e.NewExtract()
+				walkExtractors[e] = extract
+			}
+		}
+		extract(&m, p, key, value)
+		return true
+	})
+
+	for _, e := range r.extractors {
+		if e.Done != nil && e.appliesTo(&m) {
+			e.Done(&m)
+		}
+	}
+	return m, err
+}
+
+// objectType returns the top-level apiVersion and kind of the object, the nested objects and arrays are skipped
+func objectType(input []byte) (apiVersion, kind string) {
+	_ = ujson.Walk(input, func(level int, key, value []byte) bool {
+		if level == 1 {
+			switch unquote(key) {
+			case "apiVersion":
+				apiVersion = unquote(value)
+			case "kind":
+				kind = unquote(value)
+			}
+		}
+		return level == 0
+	})
+	return apiVersion, kind
+}
+
+// Extension is a typed slot of Metadata.Extensions, for the values of the extractors registered by other packages
+type Extension[T any] struct {
+	name string
+}
+
+// NewExtension returns the slot of the given name, the name should be qualified, e.g. "example.com/pkg.Field"
+func NewExtension[T any](name string) Extension[T] {
+	return Extension[T]{name: name}
+}
+
+// Name returns the key of the slot in Metadata.Extensions
+func (e Extension[T]) Name() string {
+	return e.name
+}
+
+// Get returns the value of the slot, the zero value if not set
+func (e Extension[T]) Get(m *Metadata) T {
+	v, _ := e.Lookup(m)
+	return v
+}
+
+// Lookup returns the value of the slot and whether it is set
+func (e Extension[T]) Lookup(m *Metadata) (T, bool) {
+	v, ok := m.Extensions[e.name].(T)
+	return v, ok
+}
+
+// Set sets the value of the slot
+func (e Extension[T]) Set(m *Metadata, v T) {
+	if m.Extensions == nil {
+		m.Extensions = map[string]any{}
+	}
+	m.Extensions[e.name] = v
+}
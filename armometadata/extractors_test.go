@@ -0,0 +1,118 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testReplicas = NewExtension[int]("armometadata.test/replicas")
+
+var testPaths = NewExtension[[]string]("armometadata.test/paths")
+
+func TestExtractorRegistry(t *testing.T) {
+	appendPath := func(name string) ExtractorFunc {
+		return func(m *Metadata, p string, _, _ []byte) {
+			testPaths.Set(m, append(testPaths.Get(m), name+":"+p))
+		}
+	}
+	registry := NewExtractorRegistry(metadataExtractors...)
+	registry.Register(
+		Extractor{Paths: []string{"spec."}, Extract: appendPath("spec")},
+		Extractor{Paths: []string{"spec.replicas"}, Kinds: []string{"Widget"}, Extract: func(m *Metadata, _ string, _, value []byte) {
+			if replicas := parseInt64(unquote(value)); replicas != nil {
+				testReplicas.Set(m, int(*replicas))
+			}
+		}},
+		// unfiltered extractors of the same path come after the filtered ones
+		Extractor{Paths: []string{"spec.replicas"}, Extract: appendPath("replicas")},
+		Extractor{Paths: []string{"spec.items."}, APIGroup: "example.com", Extract: appendPath("items")},
+		Extractor{APIVersion: "example.com/v1", Extract: appendPath("all"), Done: func(m *Metadata) {
+			testPaths.Set(m, append(testPaths.Get(m), "done"))
+		}},
+	)
+
+	m, err := registry.Extract([]byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"w"},
+		"spec":{"replicas":3,"items":[{"name":"a"}],"other":{"x":1}},"status":{"ready":true}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Widget", m.Kind)
+	assert.Equal(t, "w", m.Name)
+	assert.Equal(t, 3, testReplicas.Get(&m))
+	assert.Equal(t, []string{
+		// metadata.name is extracted by the more specific metadata extractor
+		"all:metadata",
+		"all:", // end of metadata
+		"all:spec",
+		// spec.replicas is extracted by the Widget extractor
+		"spec:items",
+		"items:",
+		"items:.name",
+		"items:",
+		"spec:", // end of items
+		"spec:other",
+		"spec:other.x",
+		"spec:", // end of other
+		"all:",  // end of spec
+		"all:status",
+		"all:status.ready",
+		"all:", // end of status
+		"done",
+	}, testPaths.Get(&m))
+
+	// other kinds are extracted by the unfiltered extractors only
+	m, err = registry.Extract([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","spec":{"replicas":3}}`))
+	assert.NoError(t, err)
+	_, ok := testReplicas.Lookup(&m)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"replicas:"}, testPaths.Get(&m))
+
+	// the filters apply to the fields preceding the apiVersion and kind
+	m, err = registry.Extract([]byte(`{"spec":{"replicas":4},"kind":"Widget","metadata":{"name":"w"},"apiVersion":"example.com/v1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Widget", m.Kind)
+	assert.Equal(t, "example.com/v1", m.ApiVersion)
+	assert.Equal(t, 4, testReplicas.Get(&m))
+}
+
+func TestExtractorRegistryNewExtract(t *testing.T) {
+	// the extract function counts the items of its walk
+	registry := NewExtractorRegistry(Extractor{Paths: []string{"spec.items."}, NewExtract: func() ExtractorFunc {
+		count := 0
+		return func(m *Metadata, p string, _, value []byte) {
+			if p == "" && unquote(value) == "{" {
+				count++
+				testReplicas.Set(m, count)
+			}
+		}
+	}})
+	object := []byte(`{"kind":"Widget","spec":{"items":[{"name":"a"},{"name":"b"}]}}`)
+	for range 2 {
+		m, err := registry.Extract(object)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, testReplicas.Get(&m))
+	}
+}
+
+func TestDefaultExtractorRegistryExtensions(t *testing.T) {
+	registry := NewExtractorRegistry(BuiltinExtractors()...)
+	registry.Register(Extractor{Paths: []string{"spec.replicas"}, APIGroup: "apps", Extract: func(m *Metadata, _ string, _, value []byte) {
+		if replicas := parseInt64(unquote(value)); replicas != nil {
+			testReplicas.Set(m, int(*replicas))
+		}
+	}})
+	object := []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"default"},
+		"spec":{"replicas":2,"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web"}},
+		"spec":{"containers":[{"name":"nginx","image":"nginx:1.27"}]}}}}`)
+
+	m, err := registry.Extract(object)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, testReplicas.Get(&m))
+	assert.Equal(t, map[string]string{"app": "web"}, m.SelectorMatchLabels)
+	assert.Equal(t, []string{"nginx:1.27"}, m.Images())
+
+	// the default registry is not changed
+	m, err = ExtractMetadataFromJsonBytes(object)
+	assert.NoError(t, err)
+	assert.Nil(t, m.Extensions)
+	assert.Equal(t, []string{"nginx:1.27"}, m.Images())
+}
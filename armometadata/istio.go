@@ -0,0 +1,151 @@
+package armometadata
+
+import (
+	"strings"
+)
+
+// Istio kinds
+const (
+	KindIstioPeerAuthentication = "PeerAuthentication"
+)
+
+// Istio AuthorizationPolicy actions
+const (
+	IstioActionAllow  = "ALLOW"
+	IstioActionDeny   = "DENY"
+	IstioActionAudit  = "AUDIT"
+	IstioActionCustom = "CUSTOM"
+)
+
+// Istio PeerAuthentication mTLS modes
+const (
+	MTLSModeUnset      = "UNSET"
+	MTLSModeDisable    = "DISABLE"
+	MTLSModePermissive = "PERMISSIVE"
+	MTLSModeStrict     = "STRICT"
+)
+
+// istioRuleActions maps the AuthorizationPolicy actions to the rule actions
+var istioRuleActions = map[string]string{
+	IstioActionAllow:  RuleActionAllow,
+	IstioActionDeny:   RuleActionDeny,
+	IstioActionAudit:  RuleActionAudit,
+	IstioActionCustom: RuleActionCustom,
+}
+
+// IstioPolicyAction returns the action of an AuthorizationPolicy, ALLOW if not set
+func (m *Metadata) IstioPolicyAction() string {
+	if m.IstioAction == "" {
+		return IstioActionAllow
+	}
+	return m.IstioAction
+}
+
+// istioExtractors extract the selector, action and rules of AuthorizationPolicies and the mTLS modes of PeerAuthentications
+var istioExtractors = []Extractor{
+	{Paths: []string{"spec.selector.matchLabels."}, APIGroup: IstioAPIGroup, Extract: func(m *Metadata, _ string, key, value []byte) {
+		m.NetworkPolicyPodSelectorMatchLabels[unquote(key)] = unquote(value)
+	}},
+	{Paths: []string{"spec.action"}, APIGroup: IstioAPIGroup, Extract: func(m *Metadata, _ string, _, value []byte) {
+		m.IstioAction = unquote(value)
+		// the action may follow the rules
+		for i := range m.NetworkPolicyRules {
+			m.NetworkPolicyRules[i].Action = istioRuleActions[m.IstioPolicyAction()]
+		}
+	}},
+	{Paths: []string{"spec.rules."}, APIGroup: IstioAPIGroup, Extract: func(m *Metadata, p string, _, value []byte) {
+		parseIstioRules(m, p, unquote(value))
+	}},
+	{Paths: []string{"spec.mtls.mode"}, APIGroup: IstioAPIGroup, Extract: func(m *Metadata, _ string, _, value []byte) {
+		m.MTLSMode = unquote(value)
+	}},
+	{Paths: []string{"spec.portLevelMtls."}, APIGroup: IstioAPIGroup, Extract: func(m *Metadata, p string, key, value []byte) {
+		// spec.portLevelMtls.<port>.mode
+		port, ok := strings.CutSuffix(p, ".mode")
+		if !ok || unquote(key) != "mode" {
+			return
+		}
+		if m.PortLevelMTLSModes == nil {
+			m.PortLevelMTLSModes = map[string]string{}
+		}
+		m.PortLevelMTLSModes[port] = unquote(value)
+	}},
+	{APIGroup: IstioAPIGroup, Done: setIstioIngressRules},
+}
+
+// setIstioIngressRules sets HasIngressRules for the policies enforced on the ingress traffic:
+// ALLOW, DENY and CUSTOM AuthorizationPolicies, and STRICT PeerAuthentications
+func setIstioIngressRules(m *Metadata) {
+	switch m.Kind {
+	case KindIstioAuthorizationPolicy:
+		if m.IstioPolicyAction() != IstioActionAudit {
+			setHasIngress(m)
+		}
+	case KindIstioPeerAuthentication:
+		if m.MTLSMode == MTLSModeStrict {
+			setHasIngress(m)
+		}
+	}
+}
+
+// parseIstioRules fills the rules of Istio authorization policies, p is the path within the rules list
+func parseIstioRules(m *Metadata, p string, v string) {
+	if p == "" {
+		if v == "{" {
+			m.NetworkPolicyRules = append(m.NetworkPolicyRules, NetworkPolicyRule{
+				Direction: DirectionIngress,
+				Action:    istioRuleActions[m.IstioPolicyAction()],
+			})
+		}
+		return
+	}
+	rule := m.lastRule()
+	if rule == nil {
+		return
+	}
+	switch p {
+	case ".from.":
+		if v == "{" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{})
+		}
+		return
+	case ".to.":
+		if v == "{" {
+			rule.Operations = append(rule.Operations, RuleOperation{})
+		}
+		return
+	}
+
+	if operationPath, ok := strings.CutPrefix(p, ".to..operation."); ok && len(rule.Operations) > 0 {
+		operation := &rule.Operations[len(rule.Operations)-1]
+		switch operationPath {
+		case "hosts.":
+			operation.Hosts = append(operation.Hosts, v)
+		case "ports.":
+			operation.Ports = append(operation.Ports, v)
+			// Istio authorizes TCP traffic only
+			rule.Ports = append(rule.Ports, NetworkPolicyPort{Protocol: "TCP", Port: v})
+		case "methods.":
+			operation.Methods = append(operation.Methods, v)
+		case "paths.":
+			operation.Paths = append(operation.Paths, v)
+		}
+		return
+	}
+
+	peer := rule.lastPeer()
+	sourcePath, ok := strings.CutPrefix(p, ".from..source.")
+	if peer == nil || !ok {
+		return
+	}
+	switch sourcePath {
+	case "principals.":
+		peer.Principals = append(peer.Principals, v)
+	case "namespaces.":
+		peer.Namespaces = append(peer.Namespaces, v)
+	case "ipBlocks.", "remoteIpBlocks.":
+		peer.IPBlocks = append(peer.IPBlocks, IPBlock{CIDR: v})
+	case "notIpBlocks.", "notRemoteIpBlocks.":
+		peer.NotIPBlocks = append(peer.NotIPBlocks, IPBlock{CIDR: v})
+	}
+}
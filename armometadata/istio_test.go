@@ -0,0 +1,99 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestExtractIstioAuthorizationPolicy(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"security.istio.io/v1","kind":"AuthorizationPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"selector":{"matchLabels":{"app":"api"}},"rules":[
+			{"from":[{"source":{"principals":["cluster.local/ns/prod/sa/web"],"namespaces":["prod"]}},{"source":{"ipBlocks":["10.0.0.0/8"],"notIpBlocks":["10.1.0.0/16"]}}],
+			"to":[{"operation":{"methods":["GET","HEAD"],"paths":["/api/*"],"ports":["8080"]}},{"operation":{"hosts":["api.example.com"]}}]}],
+		"action":"DENY"}}`)
+	assert.Equal(t, IstioActionDeny, policy.IstioPolicyAction())
+	assert.Equal(t, ptr.To(true), policy.HasIngressRules)
+	assert.Nil(t, policy.HasEgressRules)
+	assert.Equal(t, []NetworkPolicyRule{{
+		Direction: DirectionIngress,
+		Action:    RuleActionDeny,
+		Peers: []NetworkPolicyPeer{
+			{Principals: []string{"cluster.local/ns/prod/sa/web"}, Namespaces: []string{"prod"}},
+			{IPBlocks: []IPBlock{{CIDR: "10.0.0.0/8"}}, NotIPBlocks: []IPBlock{{CIDR: "10.1.0.0/16"}}},
+		},
+		Ports: []NetworkPolicyPort{{Protocol: "TCP", Port: "8080"}},
+		Operations: []RuleOperation{
+			{Methods: []string{"GET", "HEAD"}, Paths: []string{"/api/*"}, Ports: []string{"8080"}},
+			{Hosts: []string{"api.example.com"}},
+		},
+	}}, policy.NetworkPolicyRules)
+	assert.False(t, policy.IsolatesIngress())
+}
+
+func TestIstioPolicyActions(t *testing.T) {
+	tests := []struct {
+		name            string
+		object          string
+		hasIngressRules *bool
+		isolatesIngress bool
+	}{
+		{
+			name:            "allow nothing",
+			object:          `{"apiVersion":"security.istio.io/v1","kind":"AuthorizationPolicy","metadata":{"name":"p","namespace":"prod"},"spec":{}}`,
+			hasIngressRules: ptr.To(true),
+			isolatesIngress: true,
+		},
+		{
+			name:   "audit",
+			object: `{"apiVersion":"security.istio.io/v1beta1","kind":"AuthorizationPolicy","metadata":{"name":"p","namespace":"prod"},"spec":{"action":"AUDIT","rules":[{}]}}`,
+		},
+		{
+			name:            "custom",
+			object:          `{"apiVersion":"security.istio.io/v1","kind":"AuthorizationPolicy","metadata":{"name":"p","namespace":"prod"},"spec":{"action":"CUSTOM","provider":{"name":"ext-authz"},"rules":[{}]}}`,
+			hasIngressRules: ptr.To(true),
+		},
+		{
+			name:            "strict",
+			object:          `{"apiVersion":"security.istio.io/v1","kind":"PeerAuthentication","metadata":{"name":"p","namespace":"prod"},"spec":{"mtls":{"mode":"STRICT"}}}`,
+			hasIngressRules: ptr.To(true),
+			isolatesIngress: true,
+		},
+		{
+			name:   "permissive",
+			object: `{"apiVersion":"security.istio.io/v1","kind":"PeerAuthentication","metadata":{"name":"p","namespace":"prod"},"spec":{"mtls":{"mode":"PERMISSIVE"}}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := extractTestMetadata(t, tc.object)
+			assert.True(t, policy.IsNetworkPolicy())
+			assert.Equal(t, tc.hasIngressRules, policy.HasIngressRules)
+			assert.Equal(t, tc.isolatesIngress, policy.IsolatesIngress())
+			assert.False(t, policy.IsolatesEgress())
+		})
+	}
+}
+
+func TestIstioPeerAuthenticationPrecedence(t *testing.T) {
+	mesh := extractTestMetadata(t, `{"apiVersion":"security.istio.io/v1","kind":"PeerAuthentication","metadata":{"name":"default","namespace":"istio-system"},
+		"spec":{"mtls":{"mode":"STRICT"}}}`)
+	namespace := extractTestMetadata(t, `{"apiVersion":"security.istio.io/v1","kind":"PeerAuthentication","metadata":{"name":"default","namespace":"legacy"},
+		"spec":{"mtls":{"mode":"PERMISSIVE"}}}`)
+	workload := extractTestMetadata(t, `{"apiVersion":"security.istio.io/v1","kind":"PeerAuthentication","metadata":{"name":"db","namespace":"legacy"},
+		"spec":{"selector":{"matchLabels":{"app":"db"}},"mtls":{"mode":"UNSET"},"portLevelMtls":{"5432":{"mode":"DISABLE"}}}}`)
+	assert.Equal(t, map[string]string{"5432": MTLSModeDisable}, workload.PortLevelMTLSModes)
+
+	db := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"legacy","labels":{"app":"db"}}}`)
+	match := MatchNetworkPolicies(db, []*Metadata{mesh, workload, namespace})
+	assert.Len(t, match.Policies, 3)
+	// the UNSET workload mode inherits the namespace mode
+	assert.Equal(t, MTLSModePermissive, match.MTLSMode)
+	assert.False(t, match.IngressIsolated)
+
+	web := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"app":"web"}}}`)
+	match = MatchNetworkPolicies(web, []*Metadata{mesh, workload, namespace})
+	assert.Equal(t, MTLSModeStrict, match.MTLSMode)
+	assert.True(t, match.IngressIsolated)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"path"
 	"slices"
 	"strings"
@@ -12,10 +13,13 @@ import (
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/olvrng/ujson"
 	"github.com/spf13/viper"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	rbac "k8s.io/api/rbac/v1"
 	"k8s.io/utils/ptr"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var NamespacesListToIgnore = make([]string, 0)
@@ -41,8 +45,14 @@ func IfKubeNamespace(ns string) bool {
 	return false
 }
 
+// GenerateConfigMapName returns the config map name of a wlid, cluster-scoped resources have no namespace part
 func GenerateConfigMapName(w string) string {
-	name := strings.ToLower(fmt.Sprintf("ks-%s-%s-%s", wlid.GetNamespaceFromWlid(w), wlid.GetKindFromWlid(w), wlid.GetNameFromWlid(w)))
+	var name string
+	if namespace := wlid.GetNamespaceFromWlid(w); namespace != "" {
+		name = strings.ToLower(fmt.Sprintf("ks-%s-%s-%s", namespace, wlid.GetKindFromWlid(w), wlid.GetNameFromWlid(w)))
+	} else {
+		name = strings.ToLower(fmt.Sprintf("ks-%s-%s", wlid.GetKindFromWlid(w), wlid.GetNameFromWlid(w)))
+	}
 	if len(name) >= 63 {
 		name = hash(name)
 	}
@@ -111,153 +121,185 @@ func LoadConfig(configPath string) (*ClusterConfig, error) {
 type Metadata struct {
 	Annotations       map[string]string
 	Labels            map[string]string
-	OwnerReferences   map[string]string
+	OwnerReferences   map[string]string // fields of the owner references, the last owner wins, see OwnerReferenceList
 	CreationTimestamp string
 	ResourceVersion   string
 	Kind              string
 	ApiVersion        string
 	Namespace         string
 
+	// identity
+	Name               string
+	UID                types.UID
+	Generation         int64
+	DeletionTimestamp  string
+	Finalizers         []string
+	OwnerReferenceList []metav1.OwnerReference // owner references, in the object order
+
 	// workloads
-	PodSpecLabels map[string]string
+	SelectorMatchLabels map[string]string // spec.selector.matchLabels of workloads
+	PodSpecLabels       map[string]string
+	PodSpecAnnotations  map[string]string
+	PodSecurityContext  *corev1.PodSecurityContext
+	HostNetwork         bool
+	HostPID             bool
+	HostIPC             bool
 	// network policies
 	NetworkPolicyPodSelectorMatchLabels map[string]string
+	NetworkPolicyPodSelector            *LabelSelector      // pod selector of k8s, Cilium and AdminNetworkPolicy policies, with matchExpressions
+	NetworkPolicyNamespaceSelector      *LabelSelector      // namespace selector of the AdminNetworkPolicy subject
+	NodeSelector                        *LabelSelector      // node selector of Cilium clusterwide host policies
+	CalicoSelector                      *CalicoSelector     // selector of Calico policies, nil if invalid
+	CalicoNamespaceSelector             *CalicoSelector     // namespace selector of Calico global policies, nil if invalid
+	PolicyPriority                      *int32              // priority of AdminNetworkPolicies, lower values are evaluated first
+	PolicyOrder                         *float64            // order of Calico policies, lower values are evaluated first
+	PolicyTier                          string              // tier of Calico policies, empty for the default tier
+	NetworkPolicyTypes                  []string            // declared policy types of k8s and Calico policies, see IsolatesIngress
+	NetworkPolicyRules                  []NetworkPolicyRule // normalized ingress and egress rules
+	IstioAction                         string              // action of Istio AuthorizationPolicies, see IstioPolicyAction
+	MTLSMode                            string              // mTLS mode of Istio PeerAuthentications
+	PortLevelMTLSModes                  map[string]string   // mTLS modes of Istio PeerAuthentications by port
 	HasEgressRules                      *bool
 	HasIngressRules                     *bool
 
 	// services
 	ServicePodSelectorMatchLabels map[string]string
+	ServiceType                   corev1.ServiceType
+	ServicePorts                  []corev1.ServicePort
+	ExternalIPs                   []string
+	ExternalName                  string
+	LoadBalancerIngress           []corev1.LoadBalancerIngress // status.loadBalancer.ingress
+	// ingresses, gateways and routes
+	ClassName     string     // ingressClassName or gatewayClassName
+	Hosts         []string   // ingress hosts, gateway listener hostnames and route hostnames, without duplicates
+	TLSSecretRefs []RouteRef // ingress TLS secrets and gateway listener certificates
+	BackendRefs   []RouteRef // ingress and route backends
+	ParentRefs    []RouteRef // gateways of the routes
 	// for role bindings
 	Subjects []rbac.Subject
 	RoleRef  *rbac.RoleRef
+	// for roles and cluster roles
+	Rules           []rbac.PolicyRule
+	AggregationRule *rbac.AggregationRule
 
 	InitContainers      map[string]struct{} // map of init containers names
 	Containers          map[string]struct{} // map of containers names
 	EphemeralContainers map[string]struct{} // map of ephemeral containers names
+	ContainerInfos      []ContainerInfo     // containers of all types, in the pod spec order
+	// objects used by the pod spec
+	References References
+	// values of the extractors registered by other packages, see Extension
+	Extensions map[string]any
+}
+
+// ContainerIDs returns the identifiers of the containers of the workload, ordered by container type and name.
+// The identifiers are scoped to the pod template when the object has a pod-template-hash label.
+func (m *Metadata) ContainerIDs(w wlid.WLID) []wlid.ContainerID {
+	ids := make([]wlid.ContainerID, 0, len(m.InitContainers)+len(m.Containers)+len(m.EphemeralContainers))
+	templateHash := m.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	for _, containers := range []struct {
+		containerType wlid.ContainerType
+		names         map[string]struct{}
+	}{
+		{wlid.InitContainer, m.InitContainers},
+		{wlid.Container, m.Containers},
+		{wlid.EphemeralContainer, m.EphemeralContainers},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(containers.names)) {
+			ids = append(ids, wlid.NewContainerID(w, containers.containerType, name).WithTemplateHash(templateHash))
+		}
+	}
+	return ids
 }
 
-// ExtractMetadataFromBytes extracts metadata from the JSON bytes of a Kubernetes object
+// ExtractMetadataFromBytes extracts metadata from the JSON bytes of a Kubernetes object, see DefaultExtractorRegistry
 func ExtractMetadataFromJsonBytes(input []byte) (Metadata, error) {
-	// output values
-	m := Metadata{
-		Annotations:                         map[string]string{},
-		Labels:                              map[string]string{},
-		OwnerReferences:                     map[string]string{},
-		PodSpecLabels:                       map[string]string{},
-		NetworkPolicyPodSelectorMatchLabels: map[string]string{},
-		ServicePodSelectorMatchLabels:       map[string]string{},
-		InitContainers:                      map[string]struct{}{},
-		Containers:                          map[string]struct{}{},
-		EphemeralContainers:                 map[string]struct{}{},
-	}
-
-	currentSubjectIndex := -1
-
-	// ujson parsing
-	jsonPathElements := make([]string, 0)
-	err := ujson.Walk(input, func(level int, key, value []byte) bool {
-		if level > 0 {
-			jsonPathElements = slices.Replace(jsonPathElements, level-1, len(jsonPathElements), unquote(key))
+	return DefaultExtractorRegistry.Extract(input)
+}
+
+// metadataExtractors extract the object identity and metadata
+var metadataExtractors = []Extractor{
+	{Paths: []string{"kind"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.Kind = unquote(value) }},
+	{Paths: []string{"apiVersion"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.ApiVersion = unquote(value) }},
+	{Paths: []string{"metadata.namespace"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.Namespace = unquote(value) }},
+	{Paths: []string{"metadata.creationTimestamp"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.CreationTimestamp = unquote(value) }},
+	{Paths: []string{"metadata.resourceVersion"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.ResourceVersion = unquote(value) }},
+	{Paths: []string{"metadata.name"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.Name = unquote(value) }},
+	{Paths: []string{"metadata.uid"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.UID = types.UID(unquote(value)) }},
+	{Paths: []string{"metadata.generation"}, Extract: func(m *Metadata, _ string, _, value []byte) {
+		if generation := parseInt64(unquote(value)); generation != nil {
+			m.Generation = *generation
 		}
-		jsonPath := strings.Join(jsonPathElements, ".")
-
-		switch {
-		case jsonPath == "kind":
-			m.Kind = unquote(value)
-		case jsonPath == "apiVersion":
-			m.ApiVersion = unquote(value)
-		case jsonPath == "metadata.namespace":
-			m.Namespace = unquote(value)
-		case jsonPath == "metadata.creationTimestamp":
-			m.CreationTimestamp = unquote(value)
-		case jsonPath == "metadata.resourceVersion":
-			m.ResourceVersion = unquote(value)
-		case strings.HasPrefix(jsonPath, "metadata.annotations."):
-			m.Annotations[unquote(key)] = unquote(value)
-		case strings.HasPrefix(jsonPath, "metadata.labels."):
-			m.Labels[unquote(key)] = unquote(value)
-		case strings.HasPrefix(jsonPath, "metadata.ownerReferences.."):
-			m.OwnerReferences[unquote(key)] = unquote(value)
-		case strings.HasPrefix(jsonPath, "spec.template.metadata.labels."):
-			m.PodSpecLabels[unquote(key)] = unquote(value)
-		case strings.HasPrefix(jsonPath, "spec.jobTemplate.spec.template.metadata.labels."):
-			m.PodSpecLabels[unquote(key)] = unquote(value)
-		case strings.HasPrefix(jsonPath, "subjects."):
-			parseRoleBindingSubjects(&m, &currentSubjectIndex, key, value)
-		case strings.HasPrefix(jsonPath, "roleRef."):
-			parseRoleBindingRoleRef(&m, key, value)
-		case m.Kind == "Service" && strings.HasPrefix(jsonPath, "spec.selector."):
-			m.ServicePodSelectorMatchLabels[unquote(key)] = unquote(value)
-		// Extract container names (Deployments, StatefulSets, DaemonSets, Replicasets, Jobs, CronJobs, Pods)
-		case strings.HasPrefix(jsonPath, "spec.template.spec.initContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.jobTemplate.spec.template.spec.initContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.template.spec.initContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.initContainers..name"):
-			m.InitContainers[unquote(value)] = struct{}{}
-		case strings.HasPrefix(jsonPath, "spec.template.spec.containers..name"),
-			strings.HasPrefix(jsonPath, "spec.jobTemplate.spec.template.spec.containers..name"),
-			strings.HasPrefix(jsonPath, "spec.template.spec.containers..name"),
-			strings.HasPrefix(jsonPath, "spec.containers..name"):
-			m.Containers[unquote(value)] = struct{}{}
-		case strings.HasPrefix(jsonPath, "spec.template.spec.ephemeralContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.jobTemplate.spec.template.spec.ephemeralContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.template.spec.ephemeralContainers..name"),
-			strings.HasPrefix(jsonPath, "spec.ephemeralContainers..name"):
-			m.EphemeralContainers[unquote(value)] = struct{}{}
-		// cilium network policies
-		case m.ApiVersion == "cilium.io/v2":
-			if strings.HasPrefix(jsonPath, "spec.endpointSelector.matchLabels.") {
-				addCiliumMatchLabels(m.NetworkPolicyPodSelectorMatchLabels, key, value)
-			} else if jsonPath == "spec.egress." || jsonPath == "spec.egressDeny." {
-				setHasEgress(&m)
-			} else if jsonPath == "spec.ingress." || jsonPath == "spec.ingressDeny." {
-				setHasIngress(&m)
-			} else if jsonPath == "specs..ingress" || jsonPath == "specs..ingressDeny" {
-				setHasIngress(&m)
-			} else if jsonPath == "specs..egress" || jsonPath == "specs..egressDeny" {
-				setHasEgress(&m)
-			}
-		// k8s network policies
-		case m.ApiVersion == "networking.k8s.io/v1":
-			if strings.HasPrefix(jsonPath, "spec.podSelector.matchLabels.") {
-				m.NetworkPolicyPodSelectorMatchLabels[unquote(key)] = unquote(value)
-			} else if strings.HasPrefix(jsonPath, "spec.policyTypes.") {
-				val := unquote(value)
-				if val == "Egress" {
-					setHasEgress(&m)
-				} else if val == "Ingress" {
-					setHasIngress(&m)
-				}
-			} else if jsonPath == "spec.egress" {
-				setHasEgress(&m)
-			} else if jsonPath == "spec.ingress" {
-				setHasIngress(&m)
-			}
-		// istio network policies
-		case m.ApiVersion == "security.istio.io/v1" && strings.HasPrefix(jsonPath, "spec.selector.matchLabels."):
-			m.NetworkPolicyPodSelectorMatchLabels[unquote(key)] = unquote(value)
-		// calico
-		case m.ApiVersion == "projectcalico.org/v3":
-			if jsonPath == "spec.selector" {
-				m.NetworkPolicyPodSelectorMatchLabels = ParseCalicoSelector(value)
-			} else if strings.HasPrefix(jsonPath, "spec.types.") {
-				val := unquote(value)
-				if val == "Egress" {
-					setHasEgress(&m)
-				}
-				if val == "Ingress" {
-					setHasIngress(&m)
-				}
-			} else if jsonPath == "spec.egress" {
-				setHasEgress(&m)
-			} else if jsonPath == "spec.ingress" {
-				setHasIngress(&m)
+	}},
+	{Paths: []string{"metadata.deletionTimestamp"}, Extract: func(m *Metadata, _ string, _, value []byte) { m.DeletionTimestamp = unquote(value) }},
+	{Paths: []string{"metadata.finalizers."}, Extract: func(m *Metadata, p string, _, value []byte) {
+		if p == "" {
+			m.Finalizers = append(m.Finalizers, unquote(value))
+		}
+	}},
+	{Paths: []string{"metadata.annotations."}, Extract: func(m *Metadata, _ string, key, value []byte) { m.Annotations[unquote(key)] = unquote(value) }},
+	{Paths: []string{"metadata.labels."}, Extract: func(m *Metadata, _ string, key, value []byte) { m.Labels[unquote(key)] = unquote(value) }},
+	{Paths: []string{"metadata.ownerReferences."}, Extract: func(m *Metadata, p string, key, value []byte) {
+		if p == "" {
+			if unquote(value) == "{" {
+				m.OwnerReferenceList = append(m.OwnerReferenceList, metav1.OwnerReference{})
 			}
+			return
+		}
+		m.OwnerReferences[unquote(key)] = unquote(value)
+		parseOwnerReference(m, key, value)
+	}},
+}
+
+// workloadExtractors extract the selector and pod template metadata of workloads
+var workloadExtractors = []Extractor{
+	{Paths: []string{"spec.template.metadata.labels.", "spec.jobTemplate.spec.template.metadata.labels."},
+		Extract: func(m *Metadata, _ string, key, value []byte) { m.PodSpecLabels[unquote(key)] = unquote(value) }},
+	{Paths: []string{"spec.template.metadata.annotations.", "spec.jobTemplate.spec.template.metadata.annotations."},
+		Extract: func(m *Metadata, _ string, key, value []byte) { m.PodSpecAnnotations[unquote(key)] = unquote(value) }},
+	{Paths: []string{"spec.selector.matchLabels."},
+		Extract: func(m *Metadata, _ string, key, value []byte) { m.SelectorMatchLabels[unquote(key)] = unquote(value) }},
+}
+
+// rbacExtractors extract the subjects and role of bindings and the rules of roles.
+// They apply to all objects, as before the extractor registry.
+var rbacExtractors = []Extractor{
+	{Paths: []string{"subjects."}, Extract: func(m *Metadata, _ string, key, value []byte) { parseRoleBindingSubjects(m, key, value) }},
+	{Paths: []string{"roleRef."}, Extract: func(m *Metadata, _ string, key, value []byte) { parseRoleBindingRoleRef(m, key, value) }},
+	{Paths: []string{"rules."}, Extract: func(m *Metadata, p string, _, value []byte) { parseRoleRules(m, p, value) }},
+	{Paths: []string{"aggregationRule.clusterRoleSelectors."}, Extract: parseAggregationRule},
+}
+
+// ControllerRef returns the owner reference of the controller, nil if the object has no controller
+func (m *Metadata) ControllerRef() *metav1.OwnerReference {
+	for i := range m.OwnerReferenceList {
+		if ptr.Deref(m.OwnerReferenceList[i].Controller, false) {
+			return &m.OwnerReferenceList[i]
 		}
-		return true
-	})
+	}
+	return nil
+}
 
-	return m, err
+func parseOwnerReference(m *Metadata, key, value []byte) {
+	if len(m.OwnerReferenceList) == 0 {
+		return
+	}
+	ref := &m.OwnerReferenceList[len(m.OwnerReferenceList)-1]
+	v := unquote(value)
+	switch unquote(key) {
+	case "apiVersion":
+		ref.APIVersion = v
+	case "kind":
+		ref.Kind = v
+	case "name":
+		ref.Name = v
+	case "uid":
+		ref.UID = types.UID(v)
+	case "controller":
+		ref.Controller = parseBool(v)
+	case "blockOwnerDeletion":
+		ref.BlockOwnerDeletion = parseBool(v)
+	}
 }
 
 func setHasEgress(m *Metadata) {
@@ -272,7 +314,12 @@ func setHasIngress(m *Metadata) {
 	}
 }
 
+// ParseCalicoSelector returns the matchLabels approximation of a Calico selector, see CalicoSelector.MatchLabels.
+// Selectors with syntax errors are split on && and ==.
 func ParseCalicoSelector(value []byte) map[string]string {
+	if parsed, err := ParseCalicoSelectorExpression(unquote(value)); err == nil {
+		return parsed.MatchLabels()
+	}
 	selector := map[string]string{}
 	for _, rule := range strings.Split(unquote(value), "&&") {
 		s := strings.Split(rule, "==")
@@ -331,26 +378,103 @@ func parseRoleBindingRoleRef(m *Metadata, key, value []byte) {
 	}
 }
 
-func parseRoleBindingSubjects(m *Metadata, currentSubjectIndex *int, key, value []byte) {
+func parseRoleBindingSubjects(m *Metadata, key, value []byte) {
 	v := unquote(value)
 	if v == "{" {
 		if m.Subjects == nil {
 			m.Subjects = make([]rbac.Subject, 0)
 		}
-		*currentSubjectIndex += 1
 		m.Subjects = append(m.Subjects, rbac.Subject{})
 		return
 	}
+	if len(m.Subjects) == 0 {
+		return
+	}
 
+	subject := &m.Subjects[len(m.Subjects)-1]
 	k := unquote(key)
 	switch k {
 	case "apiGroup":
-		m.Subjects[*currentSubjectIndex].APIGroup = v
+		subject.APIGroup = v
 	case "kind":
-		m.Subjects[*currentSubjectIndex].Kind = v
+		subject.Kind = v
 	case "name":
-		m.Subjects[*currentSubjectIndex].Name = v
+		subject.Name = v
 	case "namespace":
-		m.Subjects[*currentSubjectIndex].Namespace = v
+		subject.Namespace = v
+	}
+}
+
+// parseRoleRules fills the rules of roles and cluster roles, p is the path within the rules list
+func parseRoleRules(m *Metadata, p string, value []byte) {
+	v := unquote(value)
+	if p == "" {
+		if v == "{" {
+			m.Rules = append(m.Rules, rbac.PolicyRule{})
+		}
+		return
+	}
+	if len(m.Rules) == 0 {
+		return
+	}
+	rule := &m.Rules[len(m.Rules)-1]
+	switch p {
+	case ".apiGroups.":
+		rule.APIGroups = append(rule.APIGroups, v)
+	case ".resources.":
+		rule.Resources = append(rule.Resources, v)
+	case ".resourceNames.":
+		rule.ResourceNames = append(rule.ResourceNames, v)
+	case ".verbs.":
+		rule.Verbs = append(rule.Verbs, v)
+	case ".nonResourceURLs.":
+		rule.NonResourceURLs = append(rule.NonResourceURLs, v)
+	}
+}
+
+// parseAggregationRule fills the cluster role selectors of aggregated cluster roles, p is the path within the selectors list
+func parseAggregationRule(m *Metadata, p string, key, value []byte) {
+	if m.AggregationRule == nil {
+		m.AggregationRule = &rbac.AggregationRule{}
+	}
+	v := unquote(value)
+	if p == "" {
+		if v == "{" {
+			m.AggregationRule.ClusterRoleSelectors = append(m.AggregationRule.ClusterRoleSelectors, metav1.LabelSelector{})
+		}
+		return
+	}
+	selectors := m.AggregationRule.ClusterRoleSelectors
+	if len(selectors) == 0 {
+		return
+	}
+	parseLabelSelector(&selectors[len(selectors)-1], p[1:], key, v)
+}
+
+// parseLabelSelector fills a label selector, p is the path within the selector
+func parseLabelSelector(selector *metav1.LabelSelector, p string, key []byte, v string) {
+	switch {
+	case strings.HasPrefix(p, "matchLabels."):
+		if selector.MatchLabels == nil {
+			selector.MatchLabels = map[string]string{}
+		}
+		selector.MatchLabels[unquote(key)] = v
+	case p == "matchExpressions.":
+		if v == "{" {
+			selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{})
+		}
+	case strings.HasPrefix(p, "matchExpressions.."):
+		if len(selector.MatchExpressions) == 0 {
+			return
+		}
+		requirement := &selector.MatchExpressions[len(selector.MatchExpressions)-1]
+		switch p[len("matchExpressions.."):] {
+		case "key":
+			requirement.Key = v
+		case "operator":
+			requirement.Operator = metav1.LabelSelectorOperator(v)
+		case "values.":
+			requirement.Values = append(requirement.Values, v)
+		}
 	}
 }
@@ -3,12 +3,16 @@ package armometadata
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/utils-k8s-go/wlid"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
 )
 
@@ -585,3 +589,109 @@ func TestExtractMetadataFromJsonBytesForNetworkPolicies(t *testing.T) {
 		})
 	}
 }
+
+func TestMetadataContainerIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{
+			name: "testdeployment",
+			want: []string{
+				"wlid://cluster-c/namespace-default/deployment-emailservice/initcontainer-init-container-1",
+				"wlid://cluster-c/namespace-default/deployment-emailservice/container-server",
+			},
+		},
+		{
+			name: "pod",
+			want: []string{
+				"wlid://cluster-c/namespace-default/deployment-emailservice/container-kubescape/template-549f95c69",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := os.ReadFile(fmt.Sprintf("testdata/%s.json", tt.name))
+			assert.NoError(t, err)
+			m, err := ExtractMetadataFromJsonBytes(input)
+			assert.NoError(t, err)
+			got := make([]string, 0)
+			for _, id := range m.ContainerIDs(wlid.NewK8sWLID("c", "default", "Deployment", "emailservice")) {
+				got = append(got, id.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGenerateConfigMapName(t *testing.T) {
+	assert.Equal(t, "ks-default-deployment-nginx", GenerateConfigMapName("wlid://cluster-c/namespace-default/deployment-nginx"))
+	assert.Equal(t, "ks-clusterrole-admin", GenerateConfigMapName("wlid://cluster-c/clusterrole-admin"))
+	assert.Equal(t, "ks-clusterrole-admin", GenerateConfigMapName("wlid://cluster-c/namespace-/clusterrole-admin"))
+	assert.Equal(t, hash("ks-default-deployment-"+strings.Repeat("a", 60)), GenerateConfigMapName("wlid://cluster-c/namespace-default/deployment-"+strings.Repeat("a", 60)))
+}
+
+func TestExtractIdentity(t *testing.T) {
+	input, err := os.ReadFile("testdata/pod.json")
+	assert.NoError(t, err)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "kubescape-549f95c69-pfvm7", m.Name)
+	assert.Equal(t, types.UID("833c4131-2996-49b0-88e7-59d7e78c00fb"), m.UID)
+	assert.Equal(t, []metav1.OwnerReference{{
+		APIVersion:         "apps/v1",
+		Kind:               "ReplicaSet",
+		Name:               "kubescape-549f95c69",
+		UID:                "c0ff7d3b-4183-482c-81c5-998faf0b6150",
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	}}, m.OwnerReferenceList)
+	assert.Equal(t, &m.OwnerReferenceList[0], m.ControllerRef())
+
+	input, err = os.ReadFile("testdata/testdeployment.json")
+	assert.NoError(t, err)
+	m, err = ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), m.Generation)
+	assert.Equal(t, map[string]string{"app": "emailservice"}, m.SelectorMatchLabels)
+	assert.Nil(t, m.ControllerRef())
+}
+
+func TestExtractMultipleOwners(t *testing.T) {
+	input := []byte(`{"kind":"ConfigMap","metadata":{"name":"cm","deletionTimestamp":"2024-01-01T00:00:00Z","finalizers":["a/b","c"],
+		"ownerReferences":[{"apiVersion":"v1","kind":"Pod","name":"p1","uid":"1"},{"apiVersion":"apps/v1","controller":true,"kind":"Deployment","name":"d","uid":"2"}]}}`)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", m.DeletionTimestamp)
+	assert.Equal(t, []string{"a/b", "c"}, m.Finalizers)
+	assert.Equal(t, 2, len(m.OwnerReferenceList))
+	assert.Equal(t, "p1", m.OwnerReferenceList[0].Name)
+	assert.Equal(t, "Deployment", m.ControllerRef().Kind)
+	// the map keeps the fields of the last owner
+	assert.Equal(t, "d", m.OwnerReferences["name"])
+}
+
+func TestExtractRoleRules(t *testing.T) {
+	input, err := os.ReadFile("testdata/clusterrole.json")
+	assert.NoError(t, err)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Equal(t, []rbac.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get", "list", "watch"}},
+		{APIGroups: []string{""}, ResourceNames: []string{"kubescape-config"}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+		{NonResourceURLs: []string{"/metrics", "/healthz/*"}, Verbs: []string{"get"}},
+	}, m.Rules)
+	assert.Equal(t, &rbac.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+		{MatchLabels: map[string]string{"rbac.authorization.k8s.io/aggregate-to-monitoring": "true"}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "rbac.example.com/aggregate-to", Operator: metav1.LabelSelectorOpIn, Values: []string{"monitoring", "view"}},
+		}},
+	}}, m.AggregationRule)
+
+	input, err = os.ReadFile("testdata/rolebinding.json")
+	assert.NoError(t, err)
+	m, err = ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.Nil(t, m.Rules)
+	assert.Nil(t, m.AggregationRule)
+}
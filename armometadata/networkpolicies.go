@@ -0,0 +1,379 @@
+package armometadata
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/labels"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/utils/ptr"
+)
+
+// network policy kinds
+const (
+	KindNetworkPolicy                  = "NetworkPolicy"
+	KindCiliumNetworkPolicy            = "CiliumNetworkPolicy"
+	KindCiliumClusterwideNetworkPolicy = "CiliumClusterwideNetworkPolicy"
+	KindCalicoGlobalNetworkPolicy      = "GlobalNetworkPolicy"
+	KindIstioAuthorizationPolicy       = "AuthorizationPolicy"
+)
+
+// network policy api groups
+const (
+	NetworkingAPIGroup = "networking.k8s.io"
+	CiliumAPIGroup     = "cilium.io"
+	CalicoAPIGroup     = "projectcalico.org"
+	IstioAPIGroup      = "security.istio.io"
+)
+
+// IstioRootNamespace is the default Istio root namespace, policies of the root namespace apply to the whole mesh
+const IstioRootNamespace = "istio-system"
+
+// virtual labels of the workloads used by Cilium and Calico selectors
+const (
+	CiliumNamespaceLabel      = "io.kubernetes.pod.namespace"
+	CiliumServiceAccountLabel = "io.cilium.k8s.policy.serviceaccount"
+	CalicoNamespaceLabel      = "projectcalico.org/namespace"
+	CalicoServiceAccountLabel = "projectcalico.org/serviceaccount"
+	CalicoOrchestratorLabel   = "projectcalico.org/orchestrator"
+)
+
+// networkPolicyExtractors extract the pod selector, rules and policy types of k8s network policies
+var networkPolicyExtractors = []Extractor{
+	{Paths: selectorPaths("spec.podSelector"), APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy},
+		Extract: func(m *Metadata, p string, key, value []byte) {
+			if strings.HasPrefix(p, "matchLabels.") {
+				m.NetworkPolicyPodSelectorMatchLabels[unquote(key)] = unquote(value)
+			}
+			parseSelectorPath(&m.NetworkPolicyPodSelector, p, key, unquote(value))
+		}},
+	{Paths: []string{"spec.egress."}, APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy},
+		Extract: func(m *Metadata, p string, key, value []byte) {
+			parseNetworkPolicyRules(m, DirectionEgress, RuleActionAllow, p, key, unquote(value))
+		}},
+	{Paths: []string{"spec.ingress."}, APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy},
+		Extract: func(m *Metadata, p string, key, value []byte) {
+			parseNetworkPolicyRules(m, DirectionIngress, RuleActionAllow, p, key, unquote(value))
+		}},
+	{Paths: []string{"spec.policyTypes."}, APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy}, Extract: parsePolicyTypes},
+	{Paths: []string{"spec.egress"}, APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy},
+		Extract: func(m *Metadata, _ string, _, _ []byte) { setHasEgress(m) }},
+	{Paths: []string{"spec.ingress"}, APIVersion: "networking.k8s.io/v1", Kinds: []string{KindNetworkPolicy},
+		Extract: func(m *Metadata, _ string, _, _ []byte) { setHasIngress(m) }},
+}
+
+// ciliumExtractors extract the endpoint and node selectors and the rules of Cilium policies
+var ciliumExtractors = []Extractor{
+	{Paths: selectorPaths("spec.endpointSelector"), APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		if strings.HasPrefix(p, "matchLabels.") {
+			addCiliumMatchLabels(m.NetworkPolicyPodSelectorMatchLabels, key, value)
+		}
+		parseSelectorPath(&m.NetworkPolicyPodSelector, p, key, unquote(value))
+	}},
+	{Paths: selectorPaths("spec.nodeSelector"), APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		parseSelectorPath(&m.NodeSelector, p, key, unquote(value))
+	}},
+	{Paths: []string{"spec.egress."}, APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		parseNetworkPolicyRules(m, DirectionEgress, RuleActionAllow, p, key, unquote(value))
+		setHasEgress(m)
+	}},
+	{Paths: []string{"spec.ingress."}, APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		parseNetworkPolicyRules(m, DirectionIngress, RuleActionAllow, p, key, unquote(value))
+		setHasIngress(m)
+	}},
+	{Paths: []string{"spec.egressDeny."}, APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		parseNetworkPolicyRules(m, DirectionEgress, RuleActionDeny, p, key, unquote(value))
+		setHasEgress(m)
+	}},
+	{Paths: []string{"spec.ingressDeny."}, APIVersion: "cilium.io/v2", Extract: func(m *Metadata, p string, key, value []byte) {
+		parseNetworkPolicyRules(m, DirectionIngress, RuleActionDeny, p, key, unquote(value))
+		setHasIngress(m)
+	}},
+	{Paths: []string{"specs..ingress", "specs..ingressDeny"}, APIVersion: "cilium.io/v2",
+		Extract: func(m *Metadata, _ string, _, _ []byte) { setHasIngress(m) }},
+	{Paths: []string{"specs..egress", "specs..egressDeny"}, APIVersion: "cilium.io/v2",
+		Extract: func(m *Metadata, _ string, _, _ []byte) { setHasEgress(m) }},
+}
+
+// calicoExtractors extract the selectors, order, tier, rules and policy types of Calico policies
+var calicoExtractors = []Extractor{
+	{Paths: []string{"spec.selector"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, value []byte) {
+		m.NetworkPolicyPodSelectorMatchLabels = ParseCalicoSelector(value)
+		m.CalicoSelector, _ = ParseCalicoSelectorExpression(unquote(value))
+	}},
+	{Paths: []string{"spec.namespaceSelector"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, value []byte) {
+		m.CalicoNamespaceSelector, _ = ParseCalicoSelectorExpression(unquote(value))
+	}},
+	{Paths: []string{"spec.order"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, value []byte) {
+		if order, err := strconv.ParseFloat(unquote(value), 64); err == nil {
+			m.PolicyOrder = ptr.To(order)
+		}
+	}},
+	{Paths: []string{"spec.tier"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, value []byte) {
+		m.PolicyTier = unquote(value)
+	}},
+	{Paths: []string{"spec.egress."}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, p string, _, value []byte) {
+		parseCalicoRules(m, DirectionEgress, p, unquote(value))
+	}},
+	{Paths: []string{"spec.ingress."}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, p string, _, value []byte) {
+		parseCalicoRules(m, DirectionIngress, p, unquote(value))
+	}},
+	{Paths: []string{"spec.types."}, APIVersion: "projectcalico.org/v3", Extract: parsePolicyTypes},
+	{Paths: []string{"spec.egress"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, _ []byte) { setHasEgress(m) }},
+	{Paths: []string{"spec.ingress"}, APIVersion: "projectcalico.org/v3", Extract: func(m *Metadata, _ string, _, _ []byte) { setHasIngress(m) }},
+}
+
+// NetworkPolicyMatch holds the network policies selecting a workload
+type NetworkPolicyMatch struct {
+	Policies []*Metadata // policies selecting the workload, in the input order
+	// the workload traffic is denied unless allowed by one of the policies
+	IngressIsolated bool
+	EgressIsolated  bool
+	// MTLSMode is the mTLS mode of the most specific Istio PeerAuthentication, empty if none applies
+	MTLSMode string
+}
+
+// APIGroup returns the group of the object apiVersion, empty for the core group
+func (m *Metadata) APIGroup() string {
+	group, _, found := strings.Cut(m.ApiVersion, "/")
+	if !found {
+		return ""
+	}
+	return group
+}
+
+// IsNetworkPolicy returns true for the k8s, AdminNetworkPolicy, Cilium, Calico and Istio network policies
+func (m *Metadata) IsNetworkPolicy() bool {
+	switch m.APIGroup() {
+	case NetworkingAPIGroup:
+		return m.Kind == KindNetworkPolicy
+	case PolicyAPIGroup:
+		return m.Kind == KindAdminNetworkPolicy || m.Kind == KindBaselineAdminNetworkPolicy
+	case CiliumAPIGroup:
+		return m.Kind == KindCiliumNetworkPolicy || m.Kind == KindCiliumClusterwideNetworkPolicy
+	case CalicoAPIGroup:
+		return m.Kind == KindNetworkPolicy || m.Kind == KindCalicoGlobalNetworkPolicy
+	case IstioAPIGroup:
+		return m.Kind == KindIstioAuthorizationPolicy || m.Kind == KindIstioPeerAuthentication
+	}
+	return false
+}
+
+// IsClusterWideNetworkPolicy returns true for the network policies selecting workloads of all namespaces
+func (m *Metadata) IsClusterWideNetworkPolicy() bool {
+	switch m.APIGroup() {
+	case PolicyAPIGroup:
+		return m.Kind == KindAdminNetworkPolicy || m.Kind == KindBaselineAdminNetworkPolicy
+	case CiliumAPIGroup:
+		return m.Kind == KindCiliumClusterwideNetworkPolicy
+	case CalicoAPIGroup:
+		return m.Kind == KindCalicoGlobalNetworkPolicy
+	case IstioAPIGroup:
+		return m.Namespace == IstioRootNamespace
+	}
+	return false
+}
+
+// PodLabels returns the labels of the pods of the workload: the pod template labels, or the labels of a Pod
+func (m *Metadata) PodLabels() map[string]string {
+	if len(m.PodSpecLabels) == 0 && m.Kind == "Pod" {
+		return m.Labels
+	}
+	return m.PodSpecLabels
+}
+
+// IsolatesIngress returns true if the policy restricts the ingress traffic of the selected workloads
+func (m *Metadata) IsolatesIngress() bool {
+	switch m.APIGroup() {
+	case NetworkingAPIGroup, CalicoAPIGroup:
+		// declared types decide alone, policies without declared types always apply to ingress
+		if len(m.NetworkPolicyTypes) > 0 {
+			return slices.Contains(m.NetworkPolicyTypes, string(networkingv1.PolicyTypeIngress))
+		}
+		return true
+	case IstioAPIGroup:
+		// only ALLOW policies deny the requests they do not match, STRICT peer authentications deny plaintext traffic
+		if m.Kind == KindIstioPeerAuthentication {
+			return m.MTLSMode == MTLSModeStrict
+		}
+		return m.IstioPolicyAction() == IstioActionAllow
+	case PolicyAPIGroup:
+		// admin policies deny only the traffic of their Deny rules
+		return m.isolatesByDefault(DirectionIngress)
+	}
+	return m.HasIngressRules != nil
+}
+
+// IsolatesEgress returns true if the policy restricts the egress traffic of the selected workloads
+func (m *Metadata) IsolatesEgress() bool {
+	switch m.APIGroup() {
+	case NetworkingAPIGroup, CalicoAPIGroup:
+		// declared types decide alone, policies without declared types apply to egress if they have egress rules
+		if len(m.NetworkPolicyTypes) > 0 {
+			return slices.Contains(m.NetworkPolicyTypes, string(networkingv1.PolicyTypeEgress))
+		}
+	case IstioAPIGroup:
+		return false
+	case PolicyAPIGroup:
+		return m.isolatesByDefault(DirectionEgress)
+	}
+	return m.HasEgressRules != nil
+}
+
+// SelectsWorkload returns true if the network policy applies to the pods of the workload.
+// Namespace selectors match the namespace name labels only, see SelectsWorkloadInNamespace.
+func (m *Metadata) SelectsWorkload(workload *Metadata) bool {
+	return m.SelectsWorkloadInNamespace(workload, nil)
+}
+
+// SelectsWorkloadInNamespace returns true if the network policy applies to the pods of the workload,
+// namespaceLabels are the labels of the workload namespace matched by the namespace selectors
+func (m *Metadata) SelectsWorkloadInNamespace(workload *Metadata, namespaceLabels map[string]string) bool {
+	if !m.IsNetworkPolicy() {
+		return false
+	}
+	if !m.IsClusterWideNetworkPolicy() && m.Namespace != workload.Namespace {
+		return false
+	}
+	if m.NodeSelector != nil {
+		// host policies select nodes, not pods
+		return false
+	}
+	nsLabels := withNamespaceNameLabels(workload.Namespace, namespaceLabels)
+	if m.CalicoNamespaceSelector != nil && !m.CalicoNamespaceSelector.Evaluate(nsLabels) {
+		return false
+	}
+	if m.APIGroup() == PolicyAPIGroup {
+		// the subject selects all the pods of the namespaces, or the pods of the namespaces selected by the pod selector
+		if m.NetworkPolicyNamespaceSelector == nil || !m.NetworkPolicyNamespaceSelector.Matches(nsLabels) {
+			return false
+		}
+		return m.NetworkPolicyPodSelector == nil || m.NetworkPolicyPodSelector.Matches(workload.PodLabels())
+	}
+	podLabels := workloadSelectorLabels(m.APIGroup(), workload)
+	if m.CalicoSelector != nil {
+		return m.CalicoSelector.Evaluate(podLabels)
+	}
+	if selector := m.NetworkPolicyPodSelector; selector != nil {
+		if m.APIGroup() == CiliumAPIGroup {
+			var ok bool
+			if selector, ok = selector.withoutCiliumSources(); !ok {
+				// reserved identities (host, world...) are not pods
+				return false
+			}
+		}
+		return selector.Matches(podLabels)
+	}
+	for k, v := range m.NetworkPolicyPodSelectorMatchLabels {
+		if m.APIGroup() == CiliumAPIGroup {
+			source, _, found := strings.Cut(k, ":")
+			if found && source == labels.LabelSourceReserved {
+				// reserved identities (host, world...) are not pods
+				return false
+			}
+			if found && isCiliumLabelSource(source) {
+				// matched by the virtual label without the source prefix
+				continue
+			}
+		}
+		if actual, ok := podLabels[k]; !ok || actual != v {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchNetworkPolicies returns the network policies selecting the workload and its isolation
+func MatchNetworkPolicies(workload *Metadata, policies []*Metadata) NetworkPolicyMatch {
+	return MatchNetworkPoliciesInNamespace(workload, nil, policies)
+}
+
+// MatchNetworkPoliciesInNamespace returns the network policies selecting the workload and its isolation,
+// namespaceLabels are the labels of the workload namespace matched by the namespace selectors
+func MatchNetworkPoliciesInNamespace(workload *Metadata, namespaceLabels map[string]string, policies []*Metadata) NetworkPolicyMatch {
+	match := NetworkPolicyMatch{Policies: make([]*Metadata, 0)}
+	mtlsScope := 0
+	for _, policy := range policies {
+		if !policy.SelectsWorkloadInNamespace(workload, namespaceLabels) {
+			continue
+		}
+		match.Policies = append(match.Policies, policy)
+		if policy.Kind == KindIstioPeerAuthentication {
+			// only the most specific peer authentication applies
+			if scope := policy.peerAuthenticationScope(); scope > mtlsScope {
+				match.MTLSMode = policy.MTLSMode
+				mtlsScope = scope
+			}
+			continue
+		}
+		match.IngressIsolated = match.IngressIsolated || policy.IsolatesIngress()
+		match.EgressIsolated = match.EgressIsolated || policy.IsolatesEgress()
+	}
+	match.IngressIsolated = match.IngressIsolated || match.MTLSMode == MTLSModeStrict
+	return match
+}
+
+// workloadSelectorLabels returns the pod labels with the virtual labels the policies of the api group can select
+func workloadSelectorLabels(apiGroup string, workload *Metadata) map[string]string {
+	podLabels := workload.PodLabels()
+	var virtual map[string]string
+	serviceAccount := workload.References.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	switch apiGroup {
+	case CiliumAPIGroup:
+		virtual = map[string]string{
+			CiliumNamespaceLabel:      workload.Namespace,
+			CiliumServiceAccountLabel: serviceAccount,
+		}
+	case CalicoAPIGroup:
+		virtual = map[string]string{
+			CalicoNamespaceLabel:      workload.Namespace,
+			CalicoServiceAccountLabel: serviceAccount,
+			CalicoOrchestratorLabel:   "k8s",
+		}
+	default:
+		return podLabels
+	}
+	for k, v := range podLabels {
+		virtual[k] = v
+	}
+	return virtual
+}
+
+func isCiliumLabelSource(source string) bool {
+	switch source {
+	case labels.LabelSourceAny, labels.LabelSourceK8s, labels.LabelSourceUnspec:
+		return true
+	}
+	return false
+}
+
+// peerAuthenticationScope returns the precedence of a PeerAuthentication setting a mTLS mode:
+// workload selector over namespace over mesh. It returns 0 for other objects and UNSET modes, which inherit the mode.
+func (m *Metadata) peerAuthenticationScope() int {
+	if m.Kind != KindIstioPeerAuthentication || m.MTLSMode == "" || m.MTLSMode == MTLSModeUnset {
+		return 0
+	}
+	switch {
+	case len(m.NetworkPolicyPodSelectorMatchLabels) > 0:
+		return 3
+	case m.Namespace != IstioRootNamespace:
+		return 2
+	}
+	return 1
+}
+
+// parsePolicyTypes fills the declared policy types of k8s and Calico policies
+func parsePolicyTypes(m *Metadata, _ string, _, value []byte) {
+	policyType := unquote(value)
+	m.NetworkPolicyTypes = append(m.NetworkPolicyTypes, policyType)
+	switch policyType {
+	case string(networkingv1.PolicyTypeEgress):
+		setHasEgress(m)
+	case string(networkingv1.PolicyTypeIngress):
+		setHasIngress(m)
+	}
+}
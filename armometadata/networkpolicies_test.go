@@ -0,0 +1,151 @@
+package armometadata
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func extractTestMetadata(t *testing.T, object string) *Metadata {
+	m, err := ExtractMetadataFromJsonBytes([]byte(object))
+	assert.NoError(t, err)
+	return &m
+}
+
+func extractTestFile(t *testing.T, filename string) *Metadata {
+	b, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	return extractTestMetadata(t, string(b))
+}
+
+func policyNames(policies []*Metadata) []string {
+	names := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		names = append(names, policy.Kind+"/"+policy.Name)
+	}
+	return names
+}
+
+func TestMatchNetworkPolicies(t *testing.T) {
+	deployment := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"frontend","namespace":"production"},
+		"spec":{"template":{"metadata":{"labels":{"app":"frontend","role":"database"}},"spec":{"serviceAccountName":"web","containers":[{"name":"nginx"}]}}}}`)
+	policies := []*Metadata{
+		// k8s egress policy without policy types isolates ingress too
+		extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"egress","namespace":"production"},
+			"spec":{"podSelector":{"matchLabels":{"app":"frontend"}},"egress":[{}]}}`),
+		// another namespace
+		extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"other-namespace","namespace":"default"},
+			"spec":{"podSelector":{}}}`),
+		// cilium virtual labels
+		extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"cilium","namespace":"production"},
+			"spec":{"endpointSelector":{"matchLabels":{"k8s:app":"frontend","io.cilium.k8s.policy.serviceaccount":"web"}}}}`),
+		extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumClusterwideNetworkPolicy","metadata":{"name":"cilium-namespace"},
+			"spec":{"endpointSelector":{"matchLabels":{"k8s:io.kubernetes.pod.namespace":"production"}},"egress":[{}]}}`),
+		extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumClusterwideNetworkPolicy","metadata":{"name":"cilium-host"},
+			"spec":{"endpointSelector":{"matchLabels":{"reserved:host":""}},"ingress":[{}]}}`),
+		extractTestFile(t, "testdata/caliconetworkpolicy.json"),
+		extractTestMetadata(t, `{"apiVersion":"projectcalico.org/v3","kind":"NetworkPolicy","metadata":{"name":"calico-backend","namespace":"production"},
+			"spec":{"selector":"app == 'backend'"}}`),
+		extractTestMetadata(t, `{"apiVersion":"security.istio.io/v1","kind":"AuthorizationPolicy","metadata":{"name":"mesh","namespace":"istio-system"},
+			"spec":{"selector":{"matchLabels":{"app":"frontend"}}}}`),
+	}
+
+	match := MatchNetworkPolicies(deployment, policies)
+	assert.Equal(t, []string{
+		"NetworkPolicy/egress",
+		"CiliumNetworkPolicy/cilium",
+		"CiliumClusterwideNetworkPolicy/cilium-namespace",
+		"NetworkPolicy/allow-tcp-6379",
+		"AuthorizationPolicy/mesh",
+	}, policyNames(match.Policies))
+	assert.True(t, match.IngressIsolated)
+	assert.True(t, match.EgressIsolated)
+
+	// the same workload in another namespace
+	deployment.Namespace = "staging"
+	match = MatchNetworkPolicies(deployment, policies)
+	assert.Equal(t, []string{"AuthorizationPolicy/mesh"}, policyNames(match.Policies))
+	assert.True(t, match.IngressIsolated)
+	assert.False(t, match.EgressIsolated)
+}
+
+func TestNetworkPolicyIsolation(t *testing.T) {
+	tests := []struct {
+		filename string
+		ingress  bool
+		egress   bool
+	}{
+		{filename: "testdata/networkpolicies/k8s/k8s-empty.json", ingress: true},
+		{filename: "testdata/networkpolicies/k8s/k8s-egress-only.json", egress: true},
+		{filename: "testdata/networkpolicies/k8s/k8s-egress-no-policy-type.json", ingress: true, egress: true},
+		{filename: "testdata/networkpolicies/k8s/k8s-ingress-only.json", ingress: true},
+		{filename: "testdata/networkpolicies/calico/egress-only.json", egress: true},
+		{filename: "testdata/networkpolicies/calico/empty.json", ingress: true},
+		{filename: "testdata/networkpolicies/cilium/empty-cilium.json"},
+		{filename: "testdata/networkpolicies/cilium/egress-deny-cilium.json", egress: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filename, func(t *testing.T) {
+			policy := extractTestFile(t, tc.filename)
+			assert.True(t, policy.IsNetworkPolicy())
+			assert.Equal(t, tc.ingress, policy.IsolatesIngress())
+			assert.Equal(t, tc.egress, policy.IsolatesEgress())
+		})
+	}
+}
+
+func TestPolicyTypesIsolation(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		ingress bool
+		egress  bool
+	}{
+		{name: "no types no rules", spec: `{"podSelector":{}}`, ingress: true},
+		{name: "no types ingress rules", spec: `{"podSelector":{},"ingress":[{}]}`, ingress: true},
+		{name: "no types egress rules", spec: `{"podSelector":{},"egress":[{}]}`, ingress: true, egress: true},
+		{name: "egress type without egress rules", spec: `{"podSelector":{},"policyTypes":["Egress"]}`, egress: true},
+		{name: "egress type with ingress rules", spec: `{"podSelector":{},"ingress":[{}],"policyTypes":["Egress"]}`, egress: true},
+		{name: "ingress type with egress rules", spec: `{"podSelector":{},"egress":[{}],"policyTypes":["Ingress"]}`, ingress: true},
+		{name: "both types", spec: `{"podSelector":{},"policyTypes":["Ingress","Egress"]}`, ingress: true, egress: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p"},"spec":`+tc.spec+`}`)
+			assert.Equal(t, tc.ingress, policy.IsolatesIngress())
+			assert.Equal(t, tc.egress, policy.IsolatesEgress())
+
+			// Calico types follow the same rules
+			calicoSpec := strings.ReplaceAll(strings.ReplaceAll(tc.spec, `"podSelector":{}`, `"selector":"all()"`), "policyTypes", "types")
+			calico := extractTestMetadata(t, `{"apiVersion":"projectcalico.org/v3","kind":"NetworkPolicy","metadata":{"name":"p"},"spec":`+calicoSpec+`}`)
+			assert.Equal(t, tc.ingress, calico.IsolatesIngress())
+			assert.Equal(t, tc.egress, calico.IsolatesEgress())
+		})
+	}
+}
+
+func TestExtractNetworkPolicyKindAfterSpec(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","metadata":{"name":"db","namespace":"prod"},
+		"spec":{"podSelector":{"matchLabels":{"app":"db"}},"egress":[{"to":[{"podSelector":{}}]}],"policyTypes":["Egress"]},
+		"kind":"NetworkPolicy"}`)
+	assert.True(t, policy.IsNetworkPolicy())
+	assert.Equal(t, map[string]string{"app": "db"}, policy.NetworkPolicyPodSelectorMatchLabels)
+	assert.Equal(t, &LabelSelector{MatchLabels: map[string]string{"app": "db"}}, policy.NetworkPolicyPodSelector)
+	assert.Equal(t, []string{"Egress"}, policy.NetworkPolicyTypes)
+	assert.NotNil(t, policy.HasEgressRules)
+	assert.Equal(t, []NetworkPolicyRule{
+		{Direction: DirectionEgress, Action: RuleActionAllow, Peers: []NetworkPolicyPeer{{PodSelector: &LabelSelector{}}}},
+	}, policy.NetworkPolicyRules)
+	assert.False(t, policy.IsolatesIngress())
+	assert.True(t, policy.IsolatesEgress())
+}
+
+func TestSelectsPod(t *testing.T) {
+	pod := extractTestFile(t, "testdata/pod.json")
+	policy := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"namespace":"`+pod.Namespace+`"},
+		"spec":{"podSelector":{}}}`)
+	assert.True(t, policy.SelectsWorkload(pod))
+	assert.False(t, pod.SelectsWorkload(pod))
+}
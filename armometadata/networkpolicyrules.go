@@ -0,0 +1,297 @@
+package armometadata
+
+import (
+	"strconv"
+	"strings"
+)
+
+// network policy rule directions
+const (
+	DirectionIngress = "Ingress"
+	DirectionEgress  = "Egress"
+)
+
+// network policy rule actions
+const (
+	RuleActionAllow  = "Allow"
+	RuleActionDeny   = "Deny"
+	RuleActionLog    = "Log"    // Calico only
+	RuleActionPass   = "Pass"   // Calico only
+	RuleActionAudit  = "Audit"  // Istio only
+	RuleActionCustom = "Custom" // Istio only
+)
+
+// IPBlock is a CIDR with optional excluded CIDRs
+type IPBlock struct {
+	CIDR   string
+	Except []string
+}
+
+// NetworkPolicyPort is a port of a rule, on the selected workloads for ingress rules and on the peers for egress rules
+type NetworkPolicyPort struct {
+	Protocol string // empty for all protocols
+	Port     string // number or name, empty for all ports
+	EndPort  int32  // last port of a port range, 0 for a single port
+}
+
+// NetworkPolicyPeer is a peer of a rule. The set fields must all match, a field with several values matches any of them.
+type NetworkPolicyPeer struct {
+	PodSelector             *LabelSelector  // podSelector of k8s peers, fromEndpoints/toEndpoints of Cilium peers
+	NamespaceSelector       *LabelSelector  // namespaceSelector of k8s peers
+	CalicoSelector          *CalicoSelector // selector and notSelector of Calico peers
+	CalicoNamespaceSelector *CalicoSelector // namespaceSelector of Calico peers
+	IPBlocks                []IPBlock       // k8s ipBlock, Cilium CIDRs, Calico nets and Istio ipBlocks
+	NotIPBlocks             []IPBlock       // Calico notNets and Istio notIpBlocks
+	Namespaces              []string        // Istio source namespaces
+	Principals              []string        // Istio source principals, e.g. cluster.local/ns/default/sa/web
+	Entities                []string        // Cilium entities, e.g. world
+	FQDNs                   []string        // Cilium toFQDNs names and patterns
+}
+
+// NetworkPolicyRule is a normalized ingress or egress rule of a k8s, Cilium, Calico or Istio policy
+type NetworkPolicyRule struct {
+	Direction string              // DirectionIngress or DirectionEgress
+	Action    string              // one of the RuleAction constants
+	Peers     []NetworkPolicyPeer // a rule without peers applies to all peers
+	Ports     []NetworkPolicyPort // a rule without ports applies to all ports
+	// Operations are the Istio operations, a rule without operations applies to all requests
+	Operations []RuleOperation
+}
+
+// RuleOperation is an Istio request operation. The set fields must all match, a field with several values matches any of them.
+type RuleOperation struct {
+	Hosts   []string
+	Ports   []string
+	Methods []string
+	Paths   []string
+}
+
+func (m *Metadata) lastRule() *NetworkPolicyRule {
+	if len(m.NetworkPolicyRules) == 0 {
+		return nil
+	}
+	return &m.NetworkPolicyRules[len(m.NetworkPolicyRules)-1]
+}
+
+func (r *NetworkPolicyRule) lastPeer() *NetworkPolicyPeer {
+	if len(r.Peers) == 0 {
+		return nil
+	}
+	return &r.Peers[len(r.Peers)-1]
+}
+
+func (r *NetworkPolicyRule) lastPort() *NetworkPolicyPort {
+	if len(r.Ports) == 0 {
+		return nil
+	}
+	return &r.Ports[len(r.Ports)-1]
+}
+
+func (p *NetworkPolicyPeer) lastIPBlock() *IPBlock {
+	if len(p.IPBlocks) == 0 {
+		return nil
+	}
+	return &p.IPBlocks[len(p.IPBlocks)-1]
+}
+
+// parseNetworkPolicyRules fills the ingress or egress rules of k8s and Cilium policies,
+// p is the path within the rules list, e.g. ".from..podSelector.matchLabels.app"
+func parseNetworkPolicyRules(m *Metadata, direction, action, p string, key []byte, v string) {
+	if p == "" {
+		if v == "{" {
+			m.NetworkPolicyRules = append(m.NetworkPolicyRules, NetworkPolicyRule{Direction: direction, Action: action})
+		}
+		return
+	}
+	rule := m.lastRule()
+	if rule == nil {
+		return
+	}
+	switch p {
+	case ".from.", ".to.":
+		// k8s peers
+		if v == "{" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{})
+		}
+		return
+	case ".fromEndpoints.", ".toEndpoints.":
+		// Cilium peers are endpoint selectors
+		if v == "{" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{PodSelector: &LabelSelector{}})
+		}
+		return
+	case ".fromCIDR", ".toCIDR", ".fromCIDRSet", ".toCIDRSet", ".fromEntities", ".toEntities", ".toFQDNs":
+		// the Cilium CIDRs, entities and FQDNs of a list are a single peer
+		if v == "[" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{})
+		}
+		return
+	case ".ports.", ".toPorts..ports.":
+		if v == "{" {
+			rule.Ports = append(rule.Ports, NetworkPolicyPort{})
+		}
+		return
+	}
+
+	if port := rule.lastPort(); port != nil {
+		if _, ok := cutAny(p, ".ports..", ".toPorts..ports.."); ok {
+			parseNetworkPolicyPort(port, key, v)
+			return
+		}
+	}
+	peer := rule.lastPeer()
+	if peer == nil {
+		return
+	}
+	switch p {
+	case ".fromCIDR.", ".toCIDR.":
+		peer.IPBlocks = append(peer.IPBlocks, IPBlock{CIDR: v})
+	case ".fromCIDRSet.", ".toCIDRSet.":
+		if v == "{" {
+			peer.IPBlocks = append(peer.IPBlocks, IPBlock{})
+		}
+	case ".fromEntities.", ".toEntities.":
+		peer.Entities = append(peer.Entities, v)
+	case ".toFQDNs..matchName", ".toFQDNs..matchPattern":
+		peer.FQDNs = append(peer.FQDNs, v)
+	default:
+		if selectorPath, ok := cutAny(p, ".fromEndpoints..", ".toEndpoints.."); ok {
+			parseSelectorPath(&peer.PodSelector, selectorPath, key, v)
+		} else if cidrPath, ok := cutAny(p, ".fromCIDRSet..", ".toCIDRSet..", ".from..ipBlock", ".to..ipBlock"); ok {
+			parseIPBlock(peer, strings.TrimPrefix(cidrPath, "."), v)
+		} else if selectorPath, ok := cutAny(p, ".from..podSelector", ".to..podSelector"); ok {
+			parseSelectorPath(&peer.PodSelector, strings.TrimPrefix(selectorPath, "."), key, v)
+		} else if selectorPath, ok := cutAny(p, ".from..namespaceSelector", ".to..namespaceSelector"); ok {
+			parseSelectorPath(&peer.NamespaceSelector, strings.TrimPrefix(selectorPath, "."), key, v)
+		}
+	}
+}
+
+// parseIPBlock fills the last ip block of the peer, p is the path within the ip block
+func parseIPBlock(peer *NetworkPolicyPeer, p string, v string) {
+	if p == "" {
+		// k8s peers have a single ip block
+		if v == "{" && len(peer.IPBlocks) == 0 {
+			peer.IPBlocks = append(peer.IPBlocks, IPBlock{})
+		}
+		return
+	}
+	ipBlock := peer.lastIPBlock()
+	if ipBlock == nil {
+		return
+	}
+	switch p {
+	case "cidr":
+		ipBlock.CIDR = v
+	case "except.":
+		ipBlock.Except = append(ipBlock.Except, v)
+	}
+}
+
+func parseNetworkPolicyPort(port *NetworkPolicyPort, key []byte, v string) {
+	switch unquote(key) {
+	case "port":
+		port.Port = v
+	case "protocol":
+		port.Protocol = v
+	case "endPort":
+		if endPort, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.EndPort = int32(endPort)
+		}
+	}
+}
+
+// parseCalicoRules fills the ingress or egress rules of Calico policies, p is the path within the rules list.
+// The peer of ingress rules is the source, the peer of egress rules is the destination.
+func parseCalicoRules(m *Metadata, direction, p string, v string) {
+	if p == "" {
+		if v == "{" {
+			m.NetworkPolicyRules = append(m.NetworkPolicyRules, NetworkPolicyRule{Direction: direction, Action: RuleActionAllow})
+		}
+		return
+	}
+	rule := m.lastRule()
+	if rule == nil {
+		return
+	}
+	peerKey := ".destination"
+	if direction == DirectionIngress {
+		peerKey = ".source"
+	}
+	switch p {
+	case ".action":
+		rule.Action = v
+		return
+	case ".protocol":
+		rule.setCalicoProtocol(v)
+		return
+	case ".destination.ports.":
+		rule.appendCalicoPort(v)
+		return
+	case peerKey:
+		if v == "{" {
+			rule.Peers = append(rule.Peers, NetworkPolicyPeer{})
+		}
+		return
+	}
+
+	peer := rule.lastPeer()
+	peerPath, ok := strings.CutPrefix(p, peerKey+".")
+	if peer == nil || !ok {
+		return
+	}
+	switch peerPath {
+	case "selector":
+		if selector, err := ParseCalicoSelectorExpression(v); err == nil {
+			peer.CalicoSelector = andCalicoSelector(peer.CalicoSelector, selector.Root)
+		}
+	case "notSelector":
+		if selector, err := ParseCalicoSelectorExpression(v); err == nil {
+			peer.CalicoSelector = andCalicoSelector(peer.CalicoSelector, CalicoNot{Expr: selector.Root})
+		}
+	case "namespaceSelector":
+		peer.CalicoNamespaceSelector, _ = ParseCalicoSelectorExpression(v)
+	case "nets.":
+		peer.IPBlocks = append(peer.IPBlocks, IPBlock{CIDR: v})
+	case "notNets.":
+		peer.NotIPBlocks = append(peer.NotIPBlocks, IPBlock{CIDR: v})
+	}
+}
+
+// andCalicoSelector returns the conjunction of the selector and the expression, selector may be nil
+func andCalicoSelector(selector *CalicoSelector, expr CalicoExpr) *CalicoSelector {
+	if selector == nil {
+		return &CalicoSelector{Root: expr}
+	}
+	return &CalicoSelector{Root: CalicoAnd{Exprs: []CalicoExpr{selector.Root, expr}}}
+}
+
+// setCalicoProtocol sets the rule protocol on its ports, a rule without ports gets a port with the protocol only
+func (r *NetworkPolicyRule) setCalicoProtocol(protocol string) {
+	if len(r.Ports) == 0 {
+		r.Ports = append(r.Ports, NetworkPolicyPort{Protocol: protocol})
+		return
+	}
+	for i := range r.Ports {
+		r.Ports[i].Protocol = protocol
+	}
+}
+
+// appendCalicoPort appends a Calico port, a number, a name or a range, e.g. "8080:8090"
+func (r *NetworkPolicyRule) appendCalicoPort(v string) {
+	port := NetworkPolicyPort{Port: v}
+	if len(r.Ports) > 0 {
+		port.Protocol = r.Ports[0].Protocol
+		if r.Ports[0].Port == "" {
+			// replace the port set by the protocol
+			r.Ports = r.Ports[:0]
+		}
+	}
+	if start, end, found := strings.Cut(v, ":"); found {
+		if endPort, err := strconv.ParseInt(end, 10, 32); err == nil {
+			port.Port = start
+			port.EndPort = int32(endPort)
+		}
+	}
+	r.Ports = append(r.Ports, port)
+}
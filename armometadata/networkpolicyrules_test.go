@@ -0,0 +1,107 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseCalicoSelector(t *testing.T, selector string) *CalicoSelector {
+	parsed, err := ParseCalicoSelectorExpression(selector)
+	assert.NoError(t, err)
+	return parsed
+}
+
+func TestExtractK8sNetworkPolicyRules(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"podSelector":{},"ingress":[{"from":[{"ipBlock":{"cidr":"10.0.0.0/8","except":["10.1.0.0/16","10.2.0.0/16"]}},{"podSelector":{"matchLabels":{"app":"a"}}}],
+		"ports":[{"port":80,"protocol":"TCP"},{"port":"metrics"},{"port":8000,"endPort":9000,"protocol":"UDP"}]}],
+		"egress":[{}]}}`)
+	assert.Equal(t, []NetworkPolicyRule{
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionAllow,
+			Peers: []NetworkPolicyPeer{
+				{IPBlocks: []IPBlock{{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16", "10.2.0.0/16"}}}},
+				{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "a"}}},
+			},
+			Ports: []NetworkPolicyPort{
+				{Protocol: "TCP", Port: "80"},
+				{Port: "metrics"},
+				{Protocol: "UDP", Port: "8000", EndPort: 9000},
+			},
+		},
+		{Direction: DirectionEgress, Action: RuleActionAllow},
+	}, policy.NetworkPolicyRules)
+}
+
+func TestExtractCiliumNetworkPolicyRules(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"endpointSelector":{},
+		"ingress":[{"fromEntities":["world","cluster"],"fromCIDRSet":[{"cidr":"10.0.0.0/8","except":["10.1.0.0/16"]},{"cidr":"192.168.0.0/16"}]}],
+		"egress":[{"toFQDNs":[{"matchName":"api.example.com"},{"matchPattern":"*.example.org"}],"toCIDR":["1.1.1.1/32"],
+			"toPorts":[{"ports":[{"port":"443","protocol":"TCP"},{"port":"8000","endPort":8100}]}]}],
+		"ingressDeny":[{"fromEndpoints":[{"matchLabels":{"k8s:app":"bad"}}],"toPorts":[{"ports":[{"port":"22"}]}]}]}}`)
+	assert.Equal(t, []NetworkPolicyRule{
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionAllow,
+			Peers: []NetworkPolicyPeer{
+				{Entities: []string{"world", "cluster"}},
+				{IPBlocks: []IPBlock{{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}, {CIDR: "192.168.0.0/16"}}},
+			},
+		},
+		{
+			Direction: DirectionEgress,
+			Action:    RuleActionAllow,
+			Peers: []NetworkPolicyPeer{
+				{FQDNs: []string{"api.example.com", "*.example.org"}},
+				{IPBlocks: []IPBlock{{CIDR: "1.1.1.1/32"}}},
+			},
+			Ports: []NetworkPolicyPort{{Protocol: "TCP", Port: "443"}, {Port: "8000", EndPort: 8100}},
+		},
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionDeny,
+			Peers:     []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchLabels: map[string]string{"k8s:app": "bad"}}}},
+			Ports:     []NetworkPolicyPort{{Port: "22"}},
+		},
+	}, policy.NetworkPolicyRules)
+}
+
+func TestExtractCalicoNetworkPolicyRules(t *testing.T) {
+	policy := extractTestFile(t, "testdata/networkpolicies/calico/ingress-egress.json")
+	assert.Equal(t, []NetworkPolicyRule{
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionAllow,
+			Peers:     []NetworkPolicyPeer{{CalicoSelector: mustParseCalicoSelector(t, "app == 'frontend'")}},
+			Ports:     []NetworkPolicyPort{{Protocol: "TCP", Port: "8080"}},
+		},
+		{
+			Direction: DirectionEgress,
+			Action:    RuleActionAllow,
+			Peers:     []NetworkPolicyPeer{{CalicoSelector: mustParseCalicoSelector(t, "app == 'database'")}},
+			Ports:     []NetworkPolicyPort{{Protocol: "TCP", Port: "5432"}},
+		},
+	}, policy.NetworkPolicyRules)
+
+	policy = extractTestMetadata(t, `{"apiVersion":"projectcalico.org/v3","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"selector":"all()","ingress":[{"action":"Deny","source":{"nets":["10.0.0.0/8"],"notNets":["10.1.0.0/16"],
+			"notSelector":"has(trusted)","selector":"role == 'web'","namespaceSelector":"team == 'a'"},
+			"destination":{"ports":["8000:8100","http"]},"protocol":"UDP"},{"action":"Log","protocol":"ICMP"}]}}`)
+	assert.Equal(t, []NetworkPolicyRule{
+		{
+			Direction: DirectionIngress,
+			Action:    RuleActionDeny,
+			Peers: []NetworkPolicyPeer{{
+				CalicoSelector:          mustParseCalicoSelector(t, "!has(trusted) && role == 'web'"),
+				CalicoNamespaceSelector: mustParseCalicoSelector(t, "team == 'a'"),
+				IPBlocks:                []IPBlock{{CIDR: "10.0.0.0/8"}},
+				NotIPBlocks:             []IPBlock{{CIDR: "10.1.0.0/16"}},
+			}},
+			Ports: []NetworkPolicyPort{{Protocol: "UDP", Port: "8000", EndPort: 8100}, {Protocol: "UDP", Port: "http"}},
+		},
+		{Direction: DirectionIngress, Action: RuleActionLog, Ports: []NetworkPolicyPort{{Protocol: "ICMP"}}},
+	}, policy.NetworkPolicyRules)
+}
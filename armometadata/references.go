@@ -0,0 +1,298 @@
+package armometadata
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReferenceSource is the pod spec field referencing an object
+type ReferenceSource string
+
+const (
+	ReferenceSourceVolume          ReferenceSource = "volume"
+	ReferenceSourceProjected       ReferenceSource = "projected"
+	ReferenceSourceEnvFrom         ReferenceSource = "envFrom"
+	ReferenceSourceEnv             ReferenceSource = "env"
+	ReferenceSourceImagePullSecret ReferenceSource = "imagePullSecret"
+)
+
+// ObjectReference is a reference from the pod spec to an object of the pod namespace
+type ObjectReference struct {
+	Name      string
+	Source    ReferenceSource
+	Volume    string // volume name of volume and projected references
+	Container string // container name of env and envFrom references
+	Key       string // key of env references
+	Optional  bool
+}
+
+// ServiceAccountTokenReference is a projected service account token
+type ServiceAccountTokenReference struct {
+	Volume            string
+	Audience          string
+	ExpirationSeconds *int64
+	Path              string
+}
+
+// HostPathReference is a hostPath volume
+type HostPathReference struct {
+	Volume string
+	Path   string
+	Type   string
+}
+
+// References lists the objects and host paths used by the pod spec, in the pod spec order
+type References struct {
+	Secrets                      []ObjectReference
+	ConfigMaps                   []ObjectReference
+	PersistentVolumeClaims       []ObjectReference
+	ServiceAccountTokens         []ServiceAccountTokenReference
+	HostPaths                    []HostPathReference
+	ServiceAccountName           string
+	AutomountServiceAccountToken *bool
+}
+
+// SecretNames returns the sorted names of the referenced secrets, including image pull secrets
+func (r *References) SecretNames() []string {
+	return referenceNames(r.Secrets)
+}
+
+// ConfigMapNames returns the sorted names of the referenced config maps
+func (r *References) ConfigMapNames() []string {
+	return referenceNames(r.ConfigMaps)
+}
+
+// ImagePullSecrets returns the names of the image pull secrets
+func (r *References) ImagePullSecrets() []string {
+	names := make([]string, 0)
+	for i := range r.Secrets {
+		if r.Secrets[i].Source == ReferenceSourceImagePullSecret {
+			names = append(names, r.Secrets[i].Name)
+		}
+	}
+	return names
+}
+
+func referenceNames(refs []ObjectReference) []string {
+	names := make([]string, 0, len(refs))
+	for i := range refs {
+		names = append(names, refs[i].Name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// parseServiceAccountReferences fills the service account of the pod spec
+func parseServiceAccountReferences(m *Metadata, _ string, key, value []byte) {
+	r := &m.References
+	v := unquote(value)
+	switch unquote(key) {
+	case "serviceAccountName":
+		r.ServiceAccountName = v
+	case "serviceAccount":
+		// deprecated alias of serviceAccountName
+		if r.ServiceAccountName == "" {
+			r.ServiceAccountName = v
+		}
+	case "automountServiceAccountToken":
+		r.AutomountServiceAccountToken = parseBool(v)
+	}
+}
+
+// parseImagePullSecrets fills the image pull secrets, p is the path within the image pull secrets list
+func parseImagePullSecrets(m *Metadata, p string, key, value []byte) {
+	r := &m.References
+	v := unquote(value)
+	switch p {
+	case "":
+		if v == "{" {
+			r.Secrets = append(r.Secrets, ObjectReference{Source: ReferenceSourceImagePullSecret})
+		}
+	case ".name":
+		setLastReference(r.Secrets, key, v)
+	}
+}
+
+// newPodVolumesExtractor returns the extract function of the references of the volumes of a walk,
+// p is the path within the volumes list. The name of the volume being parsed is set on its references when the volume ends.
+func newPodVolumesExtractor() ExtractorFunc {
+	volume := ""
+	return func(m *Metadata, p string, key, value []byte) {
+		v := unquote(value)
+		switch p {
+		case "":
+			if v == "}" {
+				m.References.setVolume(volume)
+				volume = ""
+			}
+			return
+		case ".name":
+			volume = v
+			return
+		}
+		parsePodVolumeSource(&m.References, p, key, v)
+	}
+}
+
+// parsePodVolumeSource fills the references of the source of a volume, p is the path within the volumes list
+func parsePodVolumeSource(r *References, p string, key []byte, v string) {
+	p, ok := strings.CutPrefix(p, ".")
+	if !ok {
+		return
+	}
+	source := ReferenceSourceVolume
+	if projected, ok := strings.CutPrefix(p, "projected.sources.."); ok {
+		p = projected
+		source = ReferenceSourceProjected
+	}
+	kind, field, _ := strings.Cut(p, ".")
+	switch {
+	case p == "secret" || p == "configMap" || p == "persistentVolumeClaim":
+		if v == "{" {
+			r.appendReference(p, ObjectReference{Source: source})
+		}
+	case strings.Contains(field, "."):
+		// e.g. secret.items..key
+		return
+	case kind == "secret":
+		// secret volumes use secretName, projected secrets use name
+		setLastReference(r.Secrets, key, v)
+	case kind == "configMap":
+		setLastReference(r.ConfigMaps, key, v)
+	case kind == "persistentVolumeClaim":
+		setLastReference(r.PersistentVolumeClaims, key, v)
+	case p == "serviceAccountToken":
+		if v == "{" {
+			r.ServiceAccountTokens = append(r.ServiceAccountTokens, ServiceAccountTokenReference{})
+		}
+	case strings.HasPrefix(p, "serviceAccountToken.") && len(r.ServiceAccountTokens) > 0:
+		token := &r.ServiceAccountTokens[len(r.ServiceAccountTokens)-1]
+		switch unquote(key) {
+		case "audience":
+			token.Audience = v
+		case "expirationSeconds":
+			if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+				token.ExpirationSeconds = &seconds
+			}
+		case "path":
+			token.Path = v
+		}
+	case p == "hostPath":
+		if v == "{" {
+			r.HostPaths = append(r.HostPaths, HostPathReference{})
+		}
+	case strings.HasPrefix(p, "hostPath.") && len(r.HostPaths) > 0:
+		hostPath := &r.HostPaths[len(r.HostPaths)-1]
+		switch unquote(key) {
+		case "path":
+			hostPath.Path = v
+		case "type":
+			hostPath.Type = v
+		}
+	}
+}
+
+// parseContainerReferences fills the secrets and config maps referenced by the env and envFrom of a container,
+// the container name is set on the references when the container ends
+func parseContainerReferences(m *Metadata, containerPath string, key []byte, v string) {
+	r := &m.References
+	switch containerPath {
+	case "envFrom..secretRef":
+		if v == "{" {
+			r.appendReference("secret", ObjectReference{Source: ReferenceSourceEnvFrom})
+		}
+	case "envFrom..configMapRef":
+		if v == "{" {
+			r.appendReference("configMap", ObjectReference{Source: ReferenceSourceEnvFrom})
+		}
+	case "env..valueFrom.secretKeyRef":
+		if v == "{" {
+			r.appendReference("secret", ObjectReference{Source: ReferenceSourceEnv})
+		}
+	case "env..valueFrom.configMapKeyRef":
+		if v == "{" {
+			r.appendReference("configMap", ObjectReference{Source: ReferenceSourceEnv})
+		}
+	default:
+		field, ok := cutAny(containerPath, "envFrom..secretRef.", "env..valueFrom.secretKeyRef.")
+		if ok && !strings.Contains(field, ".") {
+			setLastReference(r.Secrets, key, v)
+		}
+		field, ok = cutAny(containerPath, "envFrom..configMapRef.", "env..valueFrom.configMapKeyRef.")
+		if ok && !strings.Contains(field, ".") {
+			setLastReference(r.ConfigMaps, key, v)
+		}
+	}
+}
+
+// cutAny returns s without the first matching prefix
+func cutAny(s string, prefixes ...string) (string, bool) {
+	for _, prefix := range prefixes {
+		if after, ok := strings.CutPrefix(s, prefix); ok {
+			return after, true
+		}
+	}
+	return "", false
+}
+
+func (r *References) appendReference(kind string, ref ObjectReference) {
+	switch kind {
+	case "secret":
+		r.Secrets = append(r.Secrets, ref)
+	case "configMap":
+		r.ConfigMaps = append(r.ConfigMaps, ref)
+	case "persistentVolumeClaim":
+		r.PersistentVolumeClaims = append(r.PersistentVolumeClaims, ref)
+	}
+}
+
+// setLastReference sets a field of the last reference of the list
+func setLastReference(refs []ObjectReference, key []byte, v string) {
+	if len(refs) == 0 {
+		return
+	}
+	ref := &refs[len(refs)-1]
+	switch unquote(key) {
+	case "name", "secretName", "claimName":
+		if v != "" {
+			ref.Name = v
+		}
+	case "key":
+		ref.Key = v
+	case "optional":
+		ref.Optional = v == "true"
+	}
+}
+
+// setVolume sets the volume name of the volume references parsed since the previous volume
+func (r *References) setVolume(volume string) {
+	for _, refs := range [][]ObjectReference{r.Secrets, r.ConfigMaps, r.PersistentVolumeClaims} {
+		for i := range refs {
+			if (refs[i].Source == ReferenceSourceVolume || refs[i].Source == ReferenceSourceProjected) && refs[i].Volume == "" {
+				refs[i].Volume = volume
+			}
+		}
+	}
+	for i := range r.ServiceAccountTokens {
+		if r.ServiceAccountTokens[i].Volume == "" {
+			r.ServiceAccountTokens[i].Volume = volume
+		}
+	}
+	for i := range r.HostPaths {
+		if r.HostPaths[i].Volume == "" {
+			r.HostPaths[i].Volume = volume
+		}
+	}
+}
+
+// setContainer sets the container name of the env references parsed since the previous container
+func (r *References) setContainer(container string) {
+	for _, refs := range [][]ObjectReference{r.Secrets, r.ConfigMaps} {
+		for i := range refs {
+			if (refs[i].Source == ReferenceSourceEnv || refs[i].Source == ReferenceSourceEnvFrom) && refs[i].Container == "" {
+				refs[i].Container = container
+			}
+		}
+	}
+}
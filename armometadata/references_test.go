@@ -0,0 +1,78 @@
+package armometadata
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestExtractReferences(t *testing.T) {
+	input, err := os.ReadFile("testdata/pod.json")
+	assert.NoError(t, err)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+
+	assert.Equal(t, References{
+		Secrets: []ObjectReference{
+			{Name: "cloud-secret", Source: ReferenceSourceEnv, Container: "kubescape", Key: "account"},
+			{Name: "cloud-secret", Source: ReferenceSourceVolume, Volume: "cloud-secret"},
+		},
+		ConfigMaps: []ObjectReference{
+			{Name: "ks-cloud-config", Source: ReferenceSourceVolume, Volume: "ks-cloud-config"},
+			{Name: "host-scanner-definition", Source: ReferenceSourceVolume, Volume: "host-scanner-definition"},
+			{Name: "kube-root-ca.crt", Source: ReferenceSourceProjected, Volume: "kube-api-access-64hdm"},
+		},
+		ServiceAccountTokens: []ServiceAccountTokenReference{
+			{Volume: "kube-api-access-64hdm", ExpirationSeconds: ptr.To(int64(3607)), Path: "token"},
+		},
+		ServiceAccountName:           "kubescape",
+		AutomountServiceAccountToken: ptr.To(true),
+	}, m.References)
+	assert.Equal(t, []string{"cloud-secret"}, m.References.SecretNames())
+	assert.Equal(t, []string{"host-scanner-definition", "ks-cloud-config", "kube-root-ca.crt"}, m.References.ConfigMapNames())
+	assert.Empty(t, m.References.ImagePullSecrets())
+}
+
+func TestExtractReferencesTemplate(t *testing.T) {
+	input := []byte(`{"kind":"CronJob","spec":{"jobTemplate":{"spec":{"template":{"spec":{
+		"serviceAccount":"legacy",
+		"automountServiceAccountToken":false,
+		"imagePullSecrets":[{"name":"registry"}],
+		"initContainers":[{"envFrom":[{"configMapRef":{"name":"init-config"}}],"name":"init"}],
+		"containers":[{
+			"env":[{"name":"PASSWORD","valueFrom":{"secretKeyRef":{"key":"password","name":"db","optional":true}}}],
+			"envFrom":[{"secretRef":{"name":"app-secrets"}},{"prefix":"CFG_","configMapRef":{"name":"app-config","optional":false}}],
+			"name":"app"}],
+		"volumes":[
+			{"hostPath":{"path":"/var/run/docker.sock","type":"Socket"},"name":"docker"},
+			{"name":"data","persistentVolumeClaim":{"claimName":"data-pvc","readOnly":true}},
+			{"name":"token","projected":{"sources":[
+				{"serviceAccountToken":{"audience":"vault","path":"vault-token"}},
+				{"secret":{"items":[{"key":"tls.crt","path":"tls.crt"}],"name":"tls"}}]}},
+			{"name":"certs","secret":{"optional":true,"secretName":"certs"}}]
+	}}}}}}`)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+
+	r := m.References
+	assert.Equal(t, "legacy", r.ServiceAccountName)
+	assert.Equal(t, ptr.To(false), r.AutomountServiceAccountToken)
+	assert.Equal(t, []ObjectReference{
+		{Name: "registry", Source: ReferenceSourceImagePullSecret},
+		{Name: "db", Source: ReferenceSourceEnv, Container: "app", Key: "password", Optional: true},
+		{Name: "app-secrets", Source: ReferenceSourceEnvFrom, Container: "app"},
+		{Name: "tls", Source: ReferenceSourceProjected, Volume: "token"},
+		{Name: "certs", Source: ReferenceSourceVolume, Volume: "certs", Optional: true},
+	}, r.Secrets)
+	assert.Equal(t, []ObjectReference{
+		{Name: "init-config", Source: ReferenceSourceEnvFrom, Container: "init"},
+		{Name: "app-config", Source: ReferenceSourceEnvFrom, Container: "app"},
+	}, r.ConfigMaps)
+	assert.Equal(t, []ObjectReference{{Name: "data-pvc", Source: ReferenceSourceVolume, Volume: "data"}}, r.PersistentVolumeClaims)
+	assert.Equal(t, []HostPathReference{{Volume: "docker", Path: "/var/run/docker.sock", Type: "Socket"}}, r.HostPaths)
+	assert.Equal(t, []ServiceAccountTokenReference{{Volume: "token", Audience: "vault", Path: "vault-token"}}, r.ServiceAccountTokens)
+	assert.Equal(t, []string{"registry"}, r.ImagePullSecrets())
+	assert.Equal(t, []string{"app-secrets", "certs", "db", "registry", "tls"}, r.SecretNames())
+}
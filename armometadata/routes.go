@@ -0,0 +1,177 @@
+package armometadata
+
+import (
+	"slices"
+	"strings"
+)
+
+// GatewayAPIGroup is the api group of the Gateway API kinds
+const GatewayAPIGroup = "gateway.networking.k8s.io"
+
+// ingress and Gateway API kinds
+const (
+	KindIngress   = "Ingress"
+	KindGateway   = "Gateway"
+	KindHTTPRoute = "HTTPRoute"
+	KindGRPCRoute = "GRPCRoute"
+	KindTLSRoute  = "TLSRoute"
+	KindTCPRoute  = "TCPRoute"
+	KindUDPRoute  = "UDPRoute"
+)
+
+// RouteRef is a reference of an Ingress, a Gateway or a route to another object
+type RouteRef struct {
+	Group string
+	// Kind is empty for the default kind: Service for backends, Secret for TLS certificates and Gateway for parents
+	Kind        string
+	Namespace   string // empty for the namespace of the referencing object
+	Name        string
+	Port        string // port number or name of backends, listener port of parents
+	SectionName string // listener name of parents
+}
+
+// IsRouteKind returns true for Ingresses, Gateways and Gateway API routes
+func (m *Metadata) IsRouteKind() bool {
+	switch m.APIGroup() {
+	case NetworkingAPIGroup:
+		return m.Kind == KindIngress
+	case GatewayAPIGroup:
+		switch m.Kind {
+		case KindGateway, KindHTTPRoute, KindGRPCRoute, KindTLSRoute, KindTCPRoute, KindUDPRoute:
+			return true
+		}
+	}
+	return false
+}
+
+// RoutesToService returns true if the Ingress or route has the service as backend
+func (m *Metadata) RoutesToService(service *Metadata) bool {
+	for _, backend := range m.BackendRefs {
+		if m.backendIsService(backend, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// backendIsService returns true if the backend of the Ingress or route references the service
+func (m *Metadata) backendIsService(backend RouteRef, service *Metadata) bool {
+	if (backend.Kind != "" && backend.Kind != "Service") || backend.Group != "" {
+		return false
+	}
+	namespace := backend.Namespace
+	if namespace == "" {
+		namespace = m.Namespace
+	}
+	return backend.Name == service.Name && namespace == service.Namespace
+}
+
+// ServiceSelectsWorkload returns true if the service selects the pods of the workload
+func (m *Metadata) ServiceSelectsWorkload(workload *Metadata) bool {
+	if m.Namespace != workload.Namespace {
+		return false
+	}
+	selector := m.ServiceSelector()
+	return selector != nil && selector.Matches(workload.PodLabels())
+}
+
+// routeExtractors extract the hosts, class, TLS certificates, backends and parents of Ingresses, Gateways and routes
+var routeExtractors = []Extractor{
+	{Paths: []string{"spec."}, APIGroup: NetworkingAPIGroup, Kinds: []string{KindIngress}, Extract: parseRoute},
+	{Paths: []string{"spec."}, APIGroup: GatewayAPIGroup, Kinds: []string{KindGateway, KindHTTPRoute, KindGRPCRoute, KindTLSRoute, KindTCPRoute, KindUDPRoute},
+		Extract: parseRoute},
+}
+
+// parseRoute fills the fields of Ingresses, Gateways and routes, p is the path within the spec
+func parseRoute(m *Metadata, p string, key, value []byte) {
+	v := unquote(value)
+	switch {
+	case p == "ingressClassName" || p == "gatewayClassName":
+		m.ClassName = v
+	// ingresses
+	case p == "rules..host" || p == "tls..hosts.":
+		m.addHost(v)
+	case p == "tls..secretName":
+		m.TLSSecretRefs = append(m.TLSSecretRefs, RouteRef{Name: v})
+	case strings.HasPrefix(p, "defaultBackend.") || strings.HasPrefix(p, "rules..http.paths..backend."):
+		backendPath, _ := cutAny(p, "defaultBackend.", "rules..http.paths..backend.")
+		parseIngressBackend(m, backendPath, key, v)
+	// gateways
+	case p == "listeners..hostname":
+		m.addHost(v)
+	case p == "listeners..tls.certificateRefs.":
+		if v == "{" {
+			m.TLSSecretRefs = append(m.TLSSecretRefs, RouteRef{})
+		}
+	case strings.HasPrefix(p, "listeners..tls.certificateRefs.."):
+		setLastRouteRef(m.TLSSecretRefs, key, v)
+	// routes
+	case p == "hostnames.":
+		m.addHost(v)
+	case p == "parentRefs.":
+		if v == "{" {
+			m.ParentRefs = append(m.ParentRefs, RouteRef{})
+		}
+	case strings.HasPrefix(p, "parentRefs.."):
+		setLastRouteRef(m.ParentRefs, key, v)
+	case p == "rules..backendRefs.":
+		if v == "{" {
+			m.BackendRefs = append(m.BackendRefs, RouteRef{})
+		}
+	case strings.HasPrefix(p, "rules..backendRefs.."):
+		setLastRouteRef(m.BackendRefs, key, v)
+	}
+}
+
+// parseIngressBackend fills the service or resource backends of an Ingress, p is the path within the backend
+func parseIngressBackend(m *Metadata, p string, key []byte, v string) {
+	switch p {
+	case "service", "resource":
+		if v == "{" {
+			m.BackendRefs = append(m.BackendRefs, RouteRef{})
+		}
+		return
+	}
+	if len(m.BackendRefs) == 0 {
+		return
+	}
+	backend := &m.BackendRefs[len(m.BackendRefs)-1]
+	switch p {
+	case "service.name", "resource.name":
+		backend.Name = v
+	case "service.port.number", "service.port.name":
+		backend.Port = v
+	case "resource.apiGroup":
+		backend.Group = v
+	case "resource.kind":
+		backend.Kind = v
+	}
+}
+
+// setLastRouteRef sets a field of the last reference of the list
+func setLastRouteRef(refs []RouteRef, key []byte, v string) {
+	if len(refs) == 0 {
+		return
+	}
+	ref := &refs[len(refs)-1]
+	switch unquote(key) {
+	case "group":
+		ref.Group = v
+	case "kind":
+		ref.Kind = v
+	case "namespace":
+		ref.Namespace = v
+	case "name":
+		ref.Name = v
+	case "port":
+		ref.Port = v
+	case "sectionName":
+		ref.SectionName = v
+	}
+}
+
+func (m *Metadata) addHost(host string) {
+	if !slices.Contains(m.Hosts, host) {
+		m.Hosts = append(m.Hosts, host)
+	}
+}
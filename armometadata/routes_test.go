@@ -0,0 +1,71 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractIngress(t *testing.T) {
+	ingress := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","metadata":{"name":"web","namespace":"prod"},
+		"spec":{"ingressClassName":"nginx","defaultBackend":{"service":{"name":"default","port":{"name":"http"}}},
+		"rules":[{"host":"a.example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"service":{"name":"web","port":{"number":80}}}},
+			{"path":"/static","pathType":"Prefix","backend":{"resource":{"apiGroup":"k8s.example.com","kind":"StorageBucket","name":"assets"}}}]}},
+			{"host":"b.example.com","http":{"paths":[{"backend":{"service":{"name":"api","port":{"number":8080}}}}]}}],
+		"tls":[{"hosts":["a.example.com"],"secretName":"a-tls"},{"hosts":["b.example.com"]}]}}`)
+	assert.True(t, ingress.IsRouteKind())
+	assert.Equal(t, "nginx", ingress.ClassName)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, ingress.Hosts)
+	assert.Equal(t, []RouteRef{{Name: "a-tls"}}, ingress.TLSSecretRefs)
+	assert.Equal(t, []RouteRef{
+		{Name: "default", Port: "http"},
+		{Name: "web", Port: "80"},
+		{Group: "k8s.example.com", Kind: "StorageBucket", Name: "assets"},
+		{Name: "api", Port: "8080"},
+	}, ingress.BackendRefs)
+	assert.Empty(t, ingress.NetworkPolicyRules)
+}
+
+func TestExtractGatewayAPI(t *testing.T) {
+	gateway := extractTestMetadata(t, `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"Gateway","metadata":{"name":"gw","namespace":"infra"},
+		"spec":{"gatewayClassName":"istio","listeners":[{"name":"https","hostname":"*.example.com","port":443,"protocol":"HTTPS",
+			"tls":{"mode":"Terminate","certificateRefs":[{"kind":"Secret","name":"wildcard","namespace":"certs"}]}},{"name":"http","port":80,"protocol":"HTTP"}]}}`)
+	assert.Equal(t, "istio", gateway.ClassName)
+	assert.Equal(t, []string{"*.example.com"}, gateway.Hosts)
+	assert.Equal(t, []RouteRef{{Kind: "Secret", Namespace: "certs", Name: "wildcard"}}, gateway.TLSSecretRefs)
+
+	route := extractTestMetadata(t, `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"HTTPRoute","metadata":{"name":"web","namespace":"prod"},
+		"spec":{"hostnames":["www.example.com"],"parentRefs":[{"name":"gw","namespace":"infra","sectionName":"https"}],
+		"rules":[{"matches":[{"path":{"type":"PathPrefix","value":"/"}}],"backendRefs":[{"name":"web","port":8080,"weight":90},{"name":"web-canary","port":8080,"weight":10}]},
+			{"backendRefs":[{"name":"api","namespace":"backend","port":9090}]}]}}`)
+	assert.True(t, route.IsRouteKind())
+	assert.Equal(t, []string{"www.example.com"}, route.Hosts)
+	assert.Equal(t, []RouteRef{{Namespace: "infra", Name: "gw", SectionName: "https"}}, route.ParentRefs)
+	assert.Equal(t, []RouteRef{
+		{Name: "web", Port: "8080"},
+		{Name: "web-canary", Port: "8080"},
+		{Namespace: "backend", Name: "api", Port: "9090"},
+	}, route.BackendRefs)
+
+	grpcRoute := extractTestMetadata(t, `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"GRPCRoute","metadata":{"name":"grpc","namespace":"prod"},
+		"spec":{"parentRefs":[{"name":"gw","namespace":"infra","port":443}],"rules":[{"backendRefs":[{"name":"grpc","port":50051}]}]}}`)
+	assert.Equal(t, []RouteRef{{Namespace: "infra", Name: "gw", Port: "443"}}, grpcRoute.ParentRefs)
+	assert.Equal(t, []RouteRef{{Name: "grpc", Port: "50051"}}, grpcRoute.BackendRefs)
+}
+
+func TestRouteToWorkload(t *testing.T) {
+	route := extractTestMetadata(t, `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"HTTPRoute","metadata":{"name":"web","namespace":"prod"},
+		"spec":{"rules":[{"backendRefs":[{"name":"web","port":80},{"name":"api","namespace":"backend","port":80}]}]}}`)
+	web := extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"prod"},"spec":{"selector":{"app":"web"}}}`)
+	api := extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"api","namespace":"backend"},"spec":{"selector":{"app":"api"}}}`)
+	other := extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"staging"},"spec":{"selector":{"app":"web"}}}`)
+	assert.True(t, route.RoutesToService(web))
+	assert.True(t, route.RoutesToService(api))
+	assert.False(t, route.RoutesToService(other))
+
+	deployment := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"prod"},
+		"spec":{"template":{"metadata":{"labels":{"app":"web","version":"v1"}}}}}`)
+	assert.True(t, web.ServiceSelectsWorkload(deployment))
+	assert.False(t, other.ServiceSelectsWorkload(deployment))
+	assert.False(t, api.ServiceSelectsWorkload(deployment))
+}
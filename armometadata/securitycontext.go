@@ -0,0 +1,182 @@
+package armometadata
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// AppArmorAnnotationKeyPrefix is the prefix of the deprecated per container AppArmor profile annotation
+const AppArmorAnnotationKeyPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// parseHostNamespaces fills the host namespaces fields of the pod spec
+func parseHostNamespaces(m *Metadata, _ string, key, value []byte) {
+	v := unquote(value)
+	switch unquote(key) {
+	case "hostNetwork":
+		m.HostNetwork = v == "true"
+	case "hostPID":
+		m.HostPID = v == "true"
+	case "hostIPC":
+		m.HostIPC = v == "true"
+	}
+}
+
+// parsePodSecurityContext fills the pod security context, p is the path within the security context
+func parsePodSecurityContext(m *Metadata, p, v string) {
+	if m.PodSecurityContext == nil {
+		m.PodSecurityContext = &corev1.PodSecurityContext{}
+	}
+	sc := m.PodSecurityContext
+	switch p {
+	case "runAsUser":
+		sc.RunAsUser = parseInt64(v)
+	case "runAsGroup":
+		sc.RunAsGroup = parseInt64(v)
+	case "runAsNonRoot":
+		sc.RunAsNonRoot = parseBool(v)
+	case "fsGroup":
+		sc.FSGroup = parseInt64(v)
+	default:
+		if strings.HasPrefix(p, "seccompProfile.") {
+			sc.SeccompProfile = parseSeccompProfile(sc.SeccompProfile, p[len("seccompProfile."):], v)
+		} else if strings.HasPrefix(p, "appArmorProfile.") {
+			sc.AppArmorProfile = parseAppArmorProfile(sc.AppArmorProfile, p[len("appArmorProfile."):], v)
+		}
+	}
+}
+
+// parseContainerSecurityContext fills a container security context, p is the path within the security context
+func parseContainerSecurityContext(sc *corev1.SecurityContext, p, v string) {
+	switch p {
+	case "privileged":
+		sc.Privileged = parseBool(v)
+	case "runAsUser":
+		sc.RunAsUser = parseInt64(v)
+	case "runAsGroup":
+		sc.RunAsGroup = parseInt64(v)
+	case "runAsNonRoot":
+		sc.RunAsNonRoot = parseBool(v)
+	case "readOnlyRootFilesystem":
+		sc.ReadOnlyRootFilesystem = parseBool(v)
+	case "allowPrivilegeEscalation":
+		sc.AllowPrivilegeEscalation = parseBool(v)
+	case "capabilities.add.":
+		if sc.Capabilities == nil {
+			sc.Capabilities = &corev1.Capabilities{}
+		}
+		sc.Capabilities.Add = append(sc.Capabilities.Add, corev1.Capability(v))
+	case "capabilities.drop.":
+		if sc.Capabilities == nil {
+			sc.Capabilities = &corev1.Capabilities{}
+		}
+		sc.Capabilities.Drop = append(sc.Capabilities.Drop, corev1.Capability(v))
+	default:
+		if strings.HasPrefix(p, "seccompProfile.") {
+			sc.SeccompProfile = parseSeccompProfile(sc.SeccompProfile, p[len("seccompProfile."):], v)
+		} else if strings.HasPrefix(p, "appArmorProfile.") {
+			sc.AppArmorProfile = parseAppArmorProfile(sc.AppArmorProfile, p[len("appArmorProfile."):], v)
+		}
+	}
+}
+
+func parseSeccompProfile(profile *corev1.SeccompProfile, field, v string) *corev1.SeccompProfile {
+	if profile == nil {
+		profile = &corev1.SeccompProfile{}
+	}
+	switch field {
+	case "type":
+		profile.Type = corev1.SeccompProfileType(v)
+	case "localhostProfile":
+		profile.LocalhostProfile = &v
+	}
+	return profile
+}
+
+func parseAppArmorProfile(profile *corev1.AppArmorProfile, field, v string) *corev1.AppArmorProfile {
+	if profile == nil {
+		profile = &corev1.AppArmorProfile{}
+	}
+	switch field {
+	case "type":
+		profile.Type = corev1.AppArmorProfileType(v)
+	case "localhostProfile":
+		profile.LocalhostProfile = &v
+	}
+	return profile
+}
+
+// appArmorProfileFromAnnotation converts a deprecated AppArmor annotation value to a profile
+func appArmorProfileFromAnnotation(value string) *corev1.AppArmorProfile {
+	switch {
+	case value == corev1.DeprecatedAppArmorBetaProfileRuntimeDefault:
+		return &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeRuntimeDefault}
+	case value == corev1.DeprecatedAppArmorBetaProfileNameUnconfined:
+		return &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeUnconfined}
+	case strings.HasPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix):
+		profile := value[len(corev1.DeprecatedAppArmorBetaProfileNamePrefix):]
+		return &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeLocalhost, LocalhostProfile: &profile}
+	}
+	return nil
+}
+
+func parseBool(v string) *bool {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+func parseInt64(v string) *int64 {
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &i
+}
+
+// podAnnotations returns the annotations of the pod or of the pod template
+func (m *Metadata) podAnnotations() map[string]string {
+	if m.Kind == "Pod" {
+		return m.Annotations
+	}
+	return m.PodSpecAnnotations
+}
+
+// EffectiveSecurityContext returns the security context applied to the container:
+// the container fields take precedence over the pod fields, as in Kubernetes.
+// The AppArmor profile falls back to the deprecated container annotation before the pod field.
+func (m *Metadata) EffectiveSecurityContext(container *ContainerInfo) corev1.SecurityContext {
+	sc := corev1.SecurityContext{}
+	if container.SecurityContext != nil {
+		sc = *container.SecurityContext.DeepCopy()
+	}
+	if sc.AppArmorProfile == nil {
+		if value, ok := m.podAnnotations()[AppArmorAnnotationKeyPrefix+container.Name]; ok {
+			sc.AppArmorProfile = appArmorProfileFromAnnotation(value)
+		}
+	}
+	pod := m.PodSecurityContext
+	if pod == nil {
+		return sc
+	}
+	if sc.RunAsUser == nil && pod.RunAsUser != nil {
+		sc.RunAsUser = ptr.To(*pod.RunAsUser)
+	}
+	if sc.RunAsGroup == nil && pod.RunAsGroup != nil {
+		sc.RunAsGroup = ptr.To(*pod.RunAsGroup)
+	}
+	if sc.RunAsNonRoot == nil && pod.RunAsNonRoot != nil {
+		sc.RunAsNonRoot = ptr.To(*pod.RunAsNonRoot)
+	}
+	if sc.SeccompProfile == nil && pod.SeccompProfile != nil {
+		sc.SeccompProfile = pod.SeccompProfile.DeepCopy()
+	}
+	if sc.AppArmorProfile == nil && pod.AppArmorProfile != nil {
+		sc.AppArmorProfile = pod.AppArmorProfile.DeepCopy()
+	}
+	return sc
+}
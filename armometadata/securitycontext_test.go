@@ -0,0 +1,80 @@
+package armometadata
+
+import (
+	"os"
+	"testing"
+
+	"github.com/armosec/utils-k8s-go/wlid"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestExtractSecurityContext(t *testing.T) {
+	input, err := os.ReadFile("testdata/testdeployment.json")
+	assert.NoError(t, err)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &corev1.PodSecurityContext{
+		RunAsUser:    ptr.To(int64(1000)),
+		RunAsGroup:   ptr.To(int64(1000)),
+		RunAsNonRoot: ptr.To(true),
+		FSGroup:      ptr.To(int64(1000)),
+	}, m.PodSecurityContext)
+	assert.False(t, m.HostNetwork)
+
+	server := m.GetContainerInfo(wlid.Container, "server")
+	assert.Equal(t, &corev1.SecurityContext{
+		Privileged:               ptr.To(false),
+		ReadOnlyRootFilesystem:   ptr.To(true),
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"all"}},
+	}, server.SecurityContext)
+	assert.Equal(t, corev1.SecurityContext{
+		Privileged:               ptr.To(false),
+		ReadOnlyRootFilesystem:   ptr.To(true),
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"all"}},
+		RunAsUser:                ptr.To(int64(1000)),
+		RunAsGroup:               ptr.To(int64(1000)),
+		RunAsNonRoot:             ptr.To(true),
+	}, m.EffectiveSecurityContext(server))
+
+	// the declared security context is not modified
+	assert.Nil(t, server.SecurityContext.RunAsUser)
+}
+
+func TestEffectiveSecurityContextPrecedence(t *testing.T) {
+	input := []byte(`{"kind":"Deployment","spec":{"template":{
+		"metadata":{"annotations":{"container.apparmor.security.beta.kubernetes.io/app":"localhost/custom"}},
+		"spec":{
+			"hostNetwork":true,"hostPID":true,
+			"securityContext":{"runAsUser":1000,"seccompProfile":{"type":"RuntimeDefault"},"appArmorProfile":{"type":"RuntimeDefault"}},
+			"containers":[
+				{"name":"app","securityContext":{"runAsUser":0,"privileged":true,"capabilities":{"add":["NET_ADMIN","SYS_TIME"]}}},
+				{"name":"sidecar","securityContext":{"seccompProfile":{"type":"Localhost","localhostProfile":"profiles/audit.json"}}},
+				{"name":"plain"}
+			]}}}}`)
+	m, err := ExtractMetadataFromJsonBytes(input)
+	assert.NoError(t, err)
+	assert.True(t, m.HostNetwork)
+	assert.True(t, m.HostPID)
+	assert.False(t, m.HostIPC)
+
+	app := m.EffectiveSecurityContext(m.GetContainerInfo(wlid.Container, "app"))
+	assert.Equal(t, ptr.To(int64(0)), app.RunAsUser)
+	assert.Equal(t, ptr.To(true), app.Privileged)
+	assert.Equal(t, []corev1.Capability{"NET_ADMIN", "SYS_TIME"}, app.Capabilities.Add)
+	assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, app.SeccompProfile.Type)
+	assert.Equal(t, &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeLocalhost, LocalhostProfile: ptr.To("custom")}, app.AppArmorProfile)
+
+	sidecar := m.EffectiveSecurityContext(m.GetContainerInfo(wlid.Container, "sidecar"))
+	assert.Equal(t, ptr.To(int64(1000)), sidecar.RunAsUser)
+	assert.Equal(t, &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: ptr.To("profiles/audit.json")}, sidecar.SeccompProfile)
+	assert.Equal(t, corev1.AppArmorProfileTypeRuntimeDefault, sidecar.AppArmorProfile.Type)
+
+	plain := m.GetContainerInfo(wlid.Container, "plain")
+	assert.Nil(t, plain.SecurityContext)
+	assert.Equal(t, ptr.To(int64(1000)), m.EffectiveSecurityContext(plain).RunAsUser)
+}
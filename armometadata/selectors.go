@@ -0,0 +1,152 @@
+package armometadata
+
+import (
+	"strings"
+
+	"github.com/cilium/cilium/pkg/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+)
+
+// LabelSelector is a structured label selector (matchLabels and matchExpressions) of a policy or a service
+type LabelSelector metav1.LabelSelector
+
+// AsSelector converts the selector to a labels.Selector, a nil selector selects nothing
+func (s *LabelSelector) AsSelector() (k8slabels.Selector, error) {
+	return metav1.LabelSelectorAsSelector((*metav1.LabelSelector)(s))
+}
+
+// Matches returns true if the labels are selected, invalid selectors match nothing
+func (s *LabelSelector) Matches(l map[string]string) bool {
+	selector, err := s.AsSelector()
+	if err != nil {
+		return false
+	}
+	return selector.Matches(k8slabels.Set(l))
+}
+
+// ServiceSelector returns the selector of the pods of a service, nil if the service has no selector
+func (m *Metadata) ServiceSelector() *LabelSelector {
+	if len(m.ServicePodSelectorMatchLabels) == 0 {
+		return nil
+	}
+	return &LabelSelector{MatchLabels: m.ServicePodSelectorMatchLabels}
+}
+
+// CiliumNamespaceLabelsPrefix prefixes the namespace labels of the workloads, as matched by Cilium endpoint selectors
+const CiliumNamespaceLabelsPrefix = "io.cilium.k8s.namespace.labels."
+
+// SelectsWorkloadInNamespace returns true if the k8s or Cilium peer of a rule of the policy selects the pods of the workload,
+// namespaceLabels are the labels of the workload namespace matched by the namespace selectors.
+// Peers without selectors (ip blocks, Cilium entities...) select no workload.
+func (p *NetworkPolicyPeer) SelectsWorkloadInNamespace(policy, workload *Metadata, namespaceLabels map[string]string) bool {
+	switch policy.APIGroup() {
+	case NetworkingAPIGroup:
+		if p.PodSelector == nil && p.NamespaceSelector == nil {
+			return false
+		}
+		// without namespace selector, the pods of the policy namespace
+		if p.NamespaceSelector == nil {
+			if workload.Namespace != policy.Namespace {
+				return false
+			}
+		} else if !p.NamespaceSelector.Matches(withNamespaceNameLabels(workload.Namespace, namespaceLabels)) {
+			return false
+		}
+		return p.PodSelector == nil || p.PodSelector.Matches(workload.PodLabels())
+	case CiliumAPIGroup:
+		if p.PodSelector == nil {
+			return false
+		}
+		selector, ok := p.PodSelector.withoutCiliumSources()
+		if !ok {
+			// reserved identities (host, world...) are not pods
+			return false
+		}
+		// endpoint selectors of namespaced policies without namespace label select the pods of the policy namespace
+		if !policy.IsClusterWideNetworkPolicy() && !selector.hasKey(CiliumNamespaceLabel) && workload.Namespace != policy.Namespace {
+			return false
+		}
+		podLabels := workloadSelectorLabels(CiliumAPIGroup, workload)
+		podLabels[CiliumNamespaceLabelsPrefix+NamespaceNameLabel] = workload.Namespace
+		for k, v := range namespaceLabels {
+			podLabels[CiliumNamespaceLabelsPrefix+k] = v
+		}
+		return selector.Matches(podLabels)
+	}
+	return false
+}
+
+// hasKey returns true if the selector has a requirement on the label key
+func (s *LabelSelector) hasKey(key string) bool {
+	if _, ok := s.MatchLabels[key]; ok {
+		return true
+	}
+	for _, requirement := range s.MatchExpressions {
+		if requirement.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutCiliumSources returns the selector with the Cilium source prefixes (e.g. "k8s:") trimmed from the keys.
+// It returns false if the selector selects reserved identities, which are not pods.
+func (s *LabelSelector) withoutCiliumSources() (*LabelSelector, bool) {
+	trimmed := &LabelSelector{}
+	for k, v := range s.MatchLabels {
+		key, ok := trimCiliumSource(k)
+		if !ok {
+			return nil, false
+		}
+		if trimmed.MatchLabels == nil {
+			trimmed.MatchLabels = map[string]string{}
+		}
+		trimmed.MatchLabels[key] = v
+	}
+	for _, requirement := range s.MatchExpressions {
+		key, ok := trimCiliumSource(requirement.Key)
+		if !ok {
+			return nil, false
+		}
+		requirement.Key = key
+		trimmed.MatchExpressions = append(trimmed.MatchExpressions, requirement)
+	}
+	return trimmed, true
+}
+
+func trimCiliumSource(k string) (string, bool) {
+	source, key, found := strings.Cut(k, ":")
+	switch {
+	case !found:
+		return k, true
+	case source == labels.LabelSourceReserved:
+		return "", false
+	case isCiliumLabelSource(source):
+		return key, true
+	}
+	return k, true
+}
+
+// parseSelectorPath creates the selector when it starts and fills it, p is the path within the selector
+func parseSelectorPath(selector **LabelSelector, p string, key []byte, v string) {
+	if *selector == nil {
+		*selector = &LabelSelector{}
+	}
+	if p != "" {
+		parseLabelSelector((*metav1.LabelSelector)(*selector), p, key, v)
+	}
+}
+
+// selectorPaths returns the extractor paths of a selector: the selector itself and the paths below it
+func selectorPaths(path string) []string {
+	return []string{path, path + "."}
+}
+
+// cutSelectorPath returns the path within the selector at the given json path, empty for the selector itself
+func cutSelectorPath(jsonPath, selectorPath string) (string, bool) {
+	if jsonPath == selectorPath {
+		return "", true
+	}
+	return strings.CutPrefix(jsonPath, selectorPath+".")
+}
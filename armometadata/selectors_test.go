@@ -0,0 +1,168 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+)
+
+func TestExtractNetworkPolicySelectors(t *testing.T) {
+	policy := extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"db","namespace":"prod"},
+		"spec":{"podSelector":{"matchExpressions":[{"key":"tier","operator":"In","values":["db","cache"]},{"key":"canary","operator":"DoesNotExist"}]},
+		"ingress":[{"from":[{"namespaceSelector":{"matchLabels":{"team":"a"}},"podSelector":{"matchLabels":{"app":"api"}}},{"podSelector":{}}]}],
+		"egress":[{"to":[{"namespaceSelector":{"matchExpressions":[{"key":"env","operator":"NotIn","values":["dev"]}]}}]}]}}`)
+
+	assert.Equal(t, &LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"db", "cache"}},
+		{Key: "canary", Operator: metav1.LabelSelectorOpDoesNotExist},
+	}}, policy.NetworkPolicyPodSelector)
+	assert.Empty(t, policy.NetworkPolicyPodSelectorMatchLabels)
+	assert.Equal(t, []NetworkPolicyRule{
+		{Direction: DirectionIngress, Action: RuleActionAllow, Peers: []NetworkPolicyPeer{
+			{
+				NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				PodSelector:       &LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			},
+			{PodSelector: &LabelSelector{}},
+		}},
+		{Direction: DirectionEgress, Action: RuleActionAllow, Peers: []NetworkPolicyPeer{
+			{NamespaceSelector: &LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}},
+			}}},
+		}},
+	}, policy.NetworkPolicyRules)
+
+	db := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"tier":"db"}}}`)
+	canary := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"tier":"db","canary":"true"}}}`)
+	web := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"tier":"web"}}}`)
+	assert.True(t, policy.SelectsWorkload(db))
+	assert.False(t, policy.SelectsWorkload(canary))
+	assert.False(t, policy.SelectsWorkload(web))
+
+	assert.True(t, policy.NetworkPolicyRules[1].Peers[0].NamespaceSelector.Matches(map[string]string{"env": "prod"}))
+	assert.False(t, policy.NetworkPolicyRules[1].Peers[0].NamespaceSelector.Matches(map[string]string{"env": "dev"}))
+}
+
+func TestExtractCiliumSelectors(t *testing.T) {
+	policy := extractTestFile(t, "testdata/ciliumnetworkpolicy.json")
+	assert.Equal(t, &LabelSelector{MatchLabels: map[string]string{"any:app": "frontend"}}, policy.NetworkPolicyPodSelector)
+	assert.Len(t, policy.NetworkPolicyRules, 2)
+	assert.Equal(t, NetworkPolicyRule{Direction: DirectionEgress, Action: RuleActionAllow, Peers: []NetworkPolicyPeer{
+		{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "backend"}}},
+	}, Ports: []NetworkPolicyPort{{Port: "443"}}}, policy.NetworkPolicyRules[1])
+
+	policy = extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"endpointSelector":{"matchExpressions":[{"key":"k8s:app","operator":"In","values":["a","b"]}]}}}`)
+	assert.True(t, policy.SelectsWorkload(extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"app":"b"}}}`)))
+	assert.False(t, policy.SelectsWorkload(extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"app":"c"}}}`)))
+
+	policy = extractTestMetadata(t, `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+		"spec":{"endpointSelector":{"matchExpressions":[{"key":"reserved:host","operator":"Exists"}]}}}`)
+	assert.False(t, policy.SelectsWorkload(extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod"}}`)))
+}
+
+func TestLabelSelectorAsSelector(t *testing.T) {
+	service := extractTestFile(t, "testdata/service.json")
+	selector, err := service.ServiceSelector().AsSelector()
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(k8slabels.Set(service.ServicePodSelectorMatchLabels)))
+
+	var none *LabelSelector
+	assert.False(t, none.Matches(map[string]string{}))
+	assert.True(t, (&LabelSelector{}).Matches(map[string]string{}))
+
+	invalid := &LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: metav1.LabelSelectorOpIn}}}
+	_, err = invalid.AsSelector()
+	assert.Error(t, err)
+	assert.False(t, invalid.Matches(map[string]string{"a": ""}))
+}
+
+func TestPeerSelectsWorkloadInNamespace(t *testing.T) {
+	api := extractTestMetadata(t, `{"kind":"Pod","metadata":{"namespace":"prod","labels":{"app":"api"}}}`)
+	prod := map[string]string{"env": "prod"}
+	tests := []struct {
+		name          string
+		policy        string
+		selects       bool
+		selectsInProd bool // with the prod namespace labels
+	}{
+		{
+			name: "k8s pod selector of the policy namespace",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+				"spec":{"podSelector":{},"ingress":[{"from":[{"podSelector":{"matchLabels":{"app":"api"}}}]}]}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "k8s pod selector of another namespace",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"podSelector":{},"ingress":[{"from":[{"podSelector":{"matchLabels":{"app":"api"}}}]}]}}`,
+		},
+		{
+			name: "k8s namespace selector",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"podSelector":{},"ingress":[{"from":[{"namespaceSelector":{"matchExpressions":[{"key":"env","operator":"In","values":["prod"]}]}}]}]}}`,
+			selectsInProd: true,
+		},
+		{
+			name: "k8s namespace name and pod selectors",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"podSelector":{},"ingress":[{"from":[{"namespaceSelector":{"matchLabels":{"kubernetes.io/metadata.name":"prod"}},
+				"podSelector":{"matchExpressions":[{"key":"app","operator":"NotIn","values":["api"]}]}}]}]}}`,
+		},
+		{
+			name: "k8s empty namespace selector",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"podSelector":{},"egress":[{"to":[{"namespaceSelector":{}}]}]}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "k8s ip block",
+			policy: `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+				"spec":{"podSelector":{},"ingress":[{"from":[{"ipBlock":{"cidr":"10.0.0.0/8"}}]}]}}`,
+		},
+		{
+			name: "cilium endpoints of the policy namespace",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+				"spec":{"endpointSelector":{},"ingress":[{"fromEndpoints":[{"matchLabels":{"k8s:app":"api"}}]}]}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "cilium endpoints of another namespace",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"endpointSelector":{},"ingress":[{"fromEndpoints":[{"matchLabels":{"app":"api"}}]}]}}`,
+		},
+		{
+			name: "cilium namespace label",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"db"},
+				"spec":{"endpointSelector":{},"ingress":[{"fromEndpoints":[{"matchLabels":{"k8s:io.kubernetes.pod.namespace":"prod","app":"api"}}]}]}}`,
+			selects:       true,
+			selectsInProd: true,
+		},
+		{
+			name: "cilium namespace labels",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumClusterwideNetworkPolicy","metadata":{"name":"p"},
+				"spec":{"endpointSelector":{},"egress":[{"toEndpoints":[{"matchExpressions":[{"key":"k8s:io.cilium.k8s.namespace.labels.env","operator":"Exists"}]}]}]}}`,
+			selectsInProd: true,
+		},
+		{
+			name: "cilium entities",
+			policy: `{"apiVersion":"cilium.io/v2","kind":"CiliumNetworkPolicy","metadata":{"name":"p","namespace":"prod"},
+				"spec":{"endpointSelector":{},"ingress":[{"fromEntities":["cluster"]}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := extractTestMetadata(t, tt.policy)
+			assert.Len(t, policy.NetworkPolicyRules, 1)
+			assert.Len(t, policy.NetworkPolicyRules[0].Peers, 1)
+			peer := policy.NetworkPolicyRules[0].Peers[0]
+			assert.Equal(t, tt.selects, peer.SelectsWorkloadInNamespace(policy, api, nil))
+			assert.Equal(t, tt.selectsInProd, peer.SelectsWorkloadInNamespace(policy, api, prod))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package armometadata
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ExposureLevel is how far the pods of a workload are reachable, from the least to the most exposed
+type ExposureLevel int
+
+const (
+	ExposureNone         ExposureLevel = iota // no service selects the workload
+	ExposureClusterIP                         // reachable from the cluster only
+	ExposureNodePort                          // reachable on the node ports
+	ExposureLoadBalancer                      // reachable through a load balancer
+	ExposureExternal                          // reachable on external IPs, or routed by an Ingress or a Gateway API route
+)
+
+func (l ExposureLevel) String() string {
+	switch l {
+	case ExposureNone:
+		return "None"
+	case ExposureClusterIP:
+		return "ClusterIP"
+	case ExposureNodePort:
+		return "NodePort"
+	case ExposureLoadBalancer:
+		return "LoadBalancer"
+	case ExposureExternal:
+		return "External"
+	}
+	return "ExposureLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ExposedPort is a port of a service selecting a workload
+type ExposedPort struct {
+	Service    string // service name
+	Level      ExposureLevel
+	Protocol   corev1.Protocol
+	Port       int32
+	TargetPort intstr.IntOrString
+	NodePort   int32
+}
+
+// WorkloadExposure is the exposure of a workload by the services selecting it
+type WorkloadExposure struct {
+	Workload *Metadata
+	Level    ExposureLevel // the highest level of the ports
+	Services []*Metadata   // services selecting the workload
+	Routes   []*Metadata   // Ingresses and routes to the services
+	Ports    []ExposedPort
+}
+
+// serviceExtractors extract the selector, type, ports, external IPs and load balancer status of services
+var serviceExtractors = []Extractor{
+	{Paths: []string{"spec.selector."}, Kinds: []string{"Service"}, Extract: func(m *Metadata, _ string, key, value []byte) {
+		m.ServicePodSelectorMatchLabels[unquote(key)] = unquote(value)
+	}},
+	{Paths: []string{"spec."}, Kinds: []string{"Service"}, Extract: func(m *Metadata, p string, key, value []byte) {
+		parseServiceSpec(m, p, key, unquote(value))
+	}},
+	{Paths: []string{"status.loadBalancer.ingress."}, Kinds: []string{"Service"}, Extract: func(m *Metadata, p string, key, value []byte) {
+		parseLoadBalancerIngress(m, p, key, unquote(value))
+	}},
+}
+
+// parseServiceSpec fills the service type, ports and external IPs, p is the path within the spec
+func parseServiceSpec(m *Metadata, p string, key []byte, v string) {
+	switch p {
+	case "type":
+		m.ServiceType = corev1.ServiceType(v)
+	case "externalName":
+		m.ExternalName = v
+	case "externalIPs.":
+		m.ExternalIPs = append(m.ExternalIPs, v)
+	case "ports.":
+		if v == "{" {
+			m.ServicePorts = append(m.ServicePorts, corev1.ServicePort{})
+		}
+	case "ports..name", "ports..protocol", "ports..port", "ports..targetPort", "ports..nodePort", "ports..appProtocol":
+		if len(m.ServicePorts) > 0 {
+			parseServicePort(&m.ServicePorts[len(m.ServicePorts)-1], key, v)
+		}
+	}
+}
+
+// parseLoadBalancerIngress fills the load balancer status, p is the path within the ingress list
+func parseLoadBalancerIngress(m *Metadata, p string, key []byte, v string) {
+	switch p {
+	case "":
+		if v == "{" {
+			m.LoadBalancerIngress = append(m.LoadBalancerIngress, corev1.LoadBalancerIngress{})
+		}
+	case ".ip", ".hostname":
+		if len(m.LoadBalancerIngress) == 0 {
+			return
+		}
+		ingress := &m.LoadBalancerIngress[len(m.LoadBalancerIngress)-1]
+		if unquote(key) == "ip" {
+			ingress.IP = v
+		} else {
+			ingress.Hostname = v
+		}
+	}
+}
+
+func parseServicePort(port *corev1.ServicePort, key []byte, v string) {
+	switch unquote(key) {
+	case "name":
+		port.Name = v
+	case "protocol":
+		port.Protocol = corev1.Protocol(v)
+	case "port":
+		if p, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.Port = int32(p)
+		}
+	case "targetPort":
+		port.TargetPort = intstr.Parse(v)
+	case "nodePort":
+		if p, err := strconv.ParseInt(v, 10, 32); err == nil {
+			port.NodePort = int32(p)
+		}
+	case "appProtocol":
+		port.AppProtocol = &v
+	}
+}
+
+// ServiceExposureLevel returns the exposure level of the service ports, not taking routes into account
+func (m *Metadata) ServiceExposureLevel() ExposureLevel {
+	switch {
+	case len(m.ExternalIPs) > 0:
+		return ExposureExternal
+	case m.ServiceType == corev1.ServiceTypeLoadBalancer:
+		return ExposureLoadBalancer
+	case m.ServiceType == corev1.ServiceTypeNodePort:
+		return ExposureNodePort
+	}
+	return ExposureClusterIP
+}
+
+// routesToServicePort returns true if a backend of the route is the service port, backends without port use all ports
+func (m *Metadata) routesToServicePort(service *Metadata, port corev1.ServicePort) bool {
+	for _, backend := range m.BackendRefs {
+		if !m.backendIsService(backend, service) {
+			continue
+		}
+		if backend.Port == "" || backend.Port == port.Name || backend.Port == strconv.Itoa(int(port.Port)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveExposure returns the exposure of each workload by the services selecting it, in the workloads order.
+// routes are the Ingresses and Gateway API routes, a port routed to is exposed externally.
+func ResolveExposure(workloads, services, routes []*Metadata) []WorkloadExposure {
+	exposures := make([]WorkloadExposure, 0, len(workloads))
+	for _, workload := range workloads {
+		exposure := WorkloadExposure{Workload: workload, Services: make([]*Metadata, 0), Routes: make([]*Metadata, 0), Ports: make([]ExposedPort, 0)}
+		for _, service := range services {
+			if !service.ServiceSelectsWorkload(workload) {
+				continue
+			}
+			exposure.Services = append(exposure.Services, service)
+			for _, route := range routes {
+				if route.RoutesToService(service) {
+					exposure.Routes = append(exposure.Routes, route)
+				}
+			}
+			level := service.ServiceExposureLevel()
+			exposure.Level = max(exposure.Level, level)
+			for _, port := range service.ServicePorts {
+				exposed := ExposedPort{
+					Service:    service.Name,
+					Level:      level,
+					Protocol:   port.Protocol,
+					Port:       port.Port,
+					TargetPort: port.TargetPort,
+					NodePort:   port.NodePort,
+				}
+				for _, route := range routes {
+					if route.routesToServicePort(service, port) {
+						exposed.Level = ExposureExternal
+					}
+				}
+				exposure.Level = max(exposure.Level, exposed.Level)
+				exposure.Ports = append(exposure.Ports, exposed)
+			}
+		}
+		exposures = append(exposures, exposure)
+	}
+	return exposures
+}
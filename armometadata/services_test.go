@@ -0,0 +1,89 @@
+package armometadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestExtractService(t *testing.T) {
+	service := extractTestFile(t, "testdata/service.json")
+	assert.Equal(t, corev1.ServiceTypeClusterIP, service.ServiceType)
+	assert.Equal(t, []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 8080, TargetPort: intstr.FromInt32(8080)}}, service.ServicePorts)
+	assert.Equal(t, ExposureClusterIP, service.ServiceExposureLevel())
+
+	service = extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"lb","namespace":"prod"},
+		"spec":{"type":"LoadBalancer","externalIPs":["203.0.113.10"],"selector":{"app":"web"},
+			"ports":[{"name":"https","port":443,"targetPort":"https","nodePort":31443,"protocol":"TCP","appProtocol":"https"}]},
+		"status":{"loadBalancer":{"ingress":[{"ip":"198.51.100.1"},{"hostname":"lb.example.com"}]}}}`)
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, service.ServiceType)
+	assert.Equal(t, []string{"203.0.113.10"}, service.ExternalIPs)
+	assert.Equal(t, []corev1.LoadBalancerIngress{{IP: "198.51.100.1"}, {Hostname: "lb.example.com"}}, service.LoadBalancerIngress)
+	appProtocol := "https"
+	assert.Equal(t, []corev1.ServicePort{{
+		Name: "https", Protocol: corev1.ProtocolTCP, AppProtocol: &appProtocol, Port: 443, TargetPort: intstr.FromString("https"), NodePort: 31443,
+	}}, service.ServicePorts)
+	assert.Equal(t, map[string]string{"app": "web"}, service.ServicePodSelectorMatchLabels)
+	assert.Equal(t, ExposureExternal, service.ServiceExposureLevel())
+
+	service = extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db","namespace":"prod"},
+		"spec":{"type":"ExternalName","externalName":"db.example.com"}}`)
+	assert.Equal(t, "db.example.com", service.ExternalName)
+	assert.Nil(t, service.ServiceSelector())
+}
+
+func TestResolveExposure(t *testing.T) {
+	web := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"prod"},
+		"spec":{"template":{"metadata":{"labels":{"app":"web"}}}}}`)
+	api := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"api","namespace":"prod"},
+		"spec":{"template":{"metadata":{"labels":{"app":"api"}}}}}`)
+	db := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"db","namespace":"prod"},
+		"spec":{"template":{"metadata":{"labels":{"app":"db"}}}}}`)
+	worker := extractTestMetadata(t, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"worker","namespace":"prod"},
+		"spec":{"template":{"metadata":{"labels":{"app":"worker"}}}}}`)
+
+	services := []*Metadata{
+		extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"prod"},
+			"spec":{"selector":{"app":"web"},"ports":[{"name":"http","port":80},{"name":"metrics","port":9090}]}}`),
+		extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"api","namespace":"prod"},
+			"spec":{"type":"NodePort","selector":{"app":"api"},"ports":[{"port":8080,"nodePort":30080}]}}`),
+		extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"api-lb","namespace":"prod"},
+			"spec":{"type":"LoadBalancer","selector":{"app":"api"},"ports":[{"port":443,"nodePort":30443}]}}`),
+		extractTestMetadata(t, `{"apiVersion":"v1","kind":"Service","metadata":{"name":"db","namespace":"prod"},
+			"spec":{"selector":{"app":"db"},"ports":[{"port":5432}]}}`),
+	}
+	routes := []*Metadata{
+		extractTestMetadata(t, `{"apiVersion":"networking.k8s.io/v1","kind":"Ingress","metadata":{"name":"web","namespace":"prod"},
+			"spec":{"rules":[{"host":"www.example.com","http":{"paths":[{"backend":{"service":{"name":"web","port":{"name":"http"}}}}]}}]}}`),
+		// the port of a backend of another namespace or kind does not expose the port of the service
+		extractTestMetadata(t, `{"apiVersion":"gateway.networking.k8s.io/v1","kind":"HTTPRoute","metadata":{"name":"web","namespace":"prod"},
+			"spec":{"rules":[{"backendRefs":[{"name":"web","port":80},{"name":"web","namespace":"staging","port":9090},
+				{"group":"example.com","kind":"Backend","name":"web","port":9090}]}]}}`),
+	}
+
+	exposures := ResolveExposure([]*Metadata{web, api, db, worker}, services, routes)
+	assert.Len(t, exposures, 4)
+
+	assert.Equal(t, ExposureExternal, exposures[0].Level)
+	assert.Equal(t, routes, exposures[0].Routes)
+	assert.Equal(t, []ExposedPort{
+		{Service: "web", Level: ExposureExternal, Port: 80},
+		{Service: "web", Level: ExposureClusterIP, Port: 9090},
+	}, exposures[0].Ports)
+
+	assert.Equal(t, ExposureLoadBalancer, exposures[1].Level)
+	assert.Equal(t, []*Metadata{services[1], services[2]}, exposures[1].Services)
+	assert.Equal(t, []ExposedPort{
+		{Service: "api", Level: ExposureNodePort, Port: 8080, NodePort: 30080},
+		{Service: "api-lb", Level: ExposureLoadBalancer, Port: 443, NodePort: 30443},
+	}, exposures[1].Ports)
+
+	assert.Equal(t, ExposureClusterIP, exposures[2].Level)
+	assert.Empty(t, exposures[2].Routes)
+
+	assert.Equal(t, ExposureNone, exposures[3].Level)
+	assert.Empty(t, exposures[3].Services)
+	assert.Equal(t, "None", exposures[3].Level.String())
+}
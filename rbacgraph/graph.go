@@ -0,0 +1,424 @@
+// Package rbacgraph builds an in-memory graph of the RBAC objects of a cluster from their extracted metadata
+package rbacgraph
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/armosec/utils-k8s-go/armometadata"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RBAC kinds handled by the graph
+const (
+	KindRole               = "Role"
+	KindClusterRole        = "ClusterRole"
+	KindRoleBinding        = "RoleBinding"
+	KindClusterRoleBinding = "ClusterRoleBinding"
+	KindServiceAccount     = "ServiceAccount"
+)
+
+// ObjectRef identifies an object of the graph, the namespace is empty for cluster-scoped objects
+type ObjectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// Role is a Role or a ClusterRole
+type Role struct {
+	ObjectRef
+	Labels          map[string]string
+	Rules           []rbac.PolicyRule
+	AggregationRule *rbac.AggregationRule
+}
+
+// Binding is a RoleBinding or a ClusterRoleBinding
+type Binding struct {
+	ObjectRef
+	RoleRef  rbac.RoleRef
+	Subjects []rbac.Subject
+}
+
+// Role returns the reference of the bound role, a RoleBinding may reference a Role of its namespace or a ClusterRole
+func (b *Binding) Role() ObjectRef {
+	if b.RoleRef.Kind == KindRole {
+		return ObjectRef{Kind: KindRole, Namespace: b.Namespace, Name: b.RoleRef.Name}
+	}
+	return ObjectRef{Kind: b.RoleRef.Kind, Name: b.RoleRef.Name}
+}
+
+// Grant is a rule granted to the subjects of a binding
+type Grant struct {
+	Binding ObjectRef
+	Role    ObjectRef
+	Subject rbac.Subject
+	Rule    rbac.PolicyRule
+	// Namespace is the namespace where the rule applies, empty for cluster-wide grants (ClusterRoleBindings)
+	Namespace string
+}
+
+// Request describes an access to check, an empty namespace is a cluster-wide (or cluster-scoped) access
+type Request struct {
+	Verb         string
+	APIGroup     string
+	Resource     string // may include a subresource, e.g. pods/log
+	ResourceName string
+	Namespace    string
+	// NonResourceURL is set for non-resource requests, e.g. /metrics
+	NonResourceURL string
+}
+
+type subjectKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// Graph is a queryable graph of roles, bindings and service accounts, safe for concurrent use.
+// Objects are added and removed incrementally as they change.
+type Graph struct {
+	mu              sync.RWMutex
+	roles           map[ObjectRef]*Role
+	bindings        map[ObjectRef]*Binding
+	serviceAccounts map[ObjectRef]struct{}
+
+	// indexes
+	bindingsByRole    map[ObjectRef]map[ObjectRef]struct{}
+	bindingsBySubject map[subjectKey]map[ObjectRef]struct{}
+}
+
+// NewGraph returns an empty graph
+func NewGraph() *Graph {
+	return &Graph{
+		roles:             map[ObjectRef]*Role{},
+		bindings:          map[ObjectRef]*Binding{},
+		serviceAccounts:   map[ObjectRef]struct{}{},
+		bindingsByRole:    map[ObjectRef]map[ObjectRef]struct{}{},
+		bindingsBySubject: map[subjectKey]map[ObjectRef]struct{}{},
+	}
+}
+
+// refFromMetadata returns the reference of an object, cluster-scoped kinds have no namespace
+func refFromMetadata(m *armometadata.Metadata) ObjectRef {
+	ref := ObjectRef{Kind: m.Kind, Namespace: m.Namespace, Name: m.Name}
+	if m.Kind == KindClusterRole || m.Kind == KindClusterRoleBinding {
+		ref.Namespace = ""
+	}
+	return ref
+}
+
+// Add adds or replaces an RBAC object (Role, ClusterRole, RoleBinding, ClusterRoleBinding or ServiceAccount)
+func (g *Graph) Add(m *armometadata.Metadata) error {
+	ref := refFromMetadata(m)
+	if ref.Name == "" {
+		return fmt.Errorf("cannot add %s to the rbac graph, empty name", m.Kind)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch m.Kind {
+	case KindRole, KindClusterRole:
+		g.roles[ref] = &Role{
+			ObjectRef:       ref,
+			Labels:          m.Labels,
+			Rules:           m.Rules,
+			AggregationRule: m.AggregationRule,
+		}
+	case KindRoleBinding, KindClusterRoleBinding:
+		if m.RoleRef == nil {
+			return fmt.Errorf("cannot add %s to the rbac graph, missing roleRef", ref)
+		}
+		g.removeBinding(ref)
+		binding := &Binding{ObjectRef: ref, RoleRef: *m.RoleRef, Subjects: m.Subjects}
+		g.bindings[ref] = binding
+		addToIndex(g.bindingsByRole, binding.Role(), ref)
+		for _, subject := range binding.Subjects {
+			addToIndex(g.bindingsBySubject, keyOfSubject(subject, ref.Namespace), ref)
+		}
+	case KindServiceAccount:
+		g.serviceAccounts[ref] = struct{}{}
+	default:
+		return fmt.Errorf("cannot add %s to the rbac graph, unsupported kind", ref)
+	}
+	return nil
+}
+
+// Remove removes an object from the graph, removing an unknown object is a no-op
+func (g *Graph) Remove(ref ObjectRef) {
+	if ref.Kind == KindClusterRole || ref.Kind == KindClusterRoleBinding {
+		ref.Namespace = ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch ref.Kind {
+	case KindRole, KindClusterRole:
+		delete(g.roles, ref)
+	case KindRoleBinding, KindClusterRoleBinding:
+		g.removeBinding(ref)
+	case KindServiceAccount:
+		delete(g.serviceAccounts, ref)
+	}
+}
+
+// RemoveMetadata removes the object described by the metadata from the graph
+func (g *Graph) RemoveMetadata(m *armometadata.Metadata) {
+	g.Remove(refFromMetadata(m))
+}
+
+// removeBinding must be called with the lock held
+func (g *Graph) removeBinding(ref ObjectRef) {
+	binding, ok := g.bindings[ref]
+	if !ok {
+		return
+	}
+	delete(g.bindings, ref)
+	removeFromIndex(g.bindingsByRole, binding.Role(), ref)
+	for _, subject := range binding.Subjects {
+		removeFromIndex(g.bindingsBySubject, keyOfSubject(subject, ref.Namespace), ref)
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[ObjectRef]struct{}, key K, ref ObjectRef) {
+	if index[key] == nil {
+		index[key] = map[ObjectRef]struct{}{}
+	}
+	index[key][ref] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[ObjectRef]struct{}, key K, ref ObjectRef) {
+	delete(index[key], ref)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// keyOfSubject returns the index key of a binding subject, service accounts without namespace belong to the binding namespace
+func keyOfSubject(subject rbac.Subject, bindingNamespace string) subjectKey {
+	if subject.Kind != rbac.ServiceAccountKind {
+		return subjectKey{kind: subject.Kind, name: subject.Name}
+	}
+	namespace := subject.Namespace
+	if namespace == "" {
+		namespace = bindingNamespace
+	}
+	return subjectKey{kind: subject.Kind, namespace: namespace, name: subject.Name}
+}
+
+// subjectKeys returns the keys matching a subject, service accounts also match their implicit groups
+func subjectKeys(subject rbac.Subject) []subjectKey {
+	if subject.Kind != rbac.ServiceAccountKind {
+		return []subjectKey{{kind: subject.Kind, name: subject.Name}}
+	}
+	return []subjectKey{
+		{kind: subject.Kind, namespace: subject.Namespace, name: subject.Name},
+		{kind: rbac.GroupKind, name: "system:serviceaccounts"},
+		{kind: rbac.GroupKind, name: "system:serviceaccounts:" + subject.Namespace},
+		{kind: rbac.GroupKind, name: "system:authenticated"},
+	}
+}
+
+// Role returns a role or cluster role, nil if not found
+func (g *Graph) Role(ref ObjectRef) *Role {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.roles[ref]
+}
+
+// Binding returns a role binding or cluster role binding, nil if not found
+func (g *Graph) Binding(ref ObjectRef) *Binding {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.bindings[ref]
+}
+
+// HasServiceAccount returns true if the service account was added to the graph
+func (g *Graph) HasServiceAccount(namespace, name string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.serviceAccounts[ObjectRef{Kind: KindServiceAccount, Namespace: namespace, Name: name}]
+	return ok
+}
+
+// RoleRules returns the rules of a role, aggregated cluster roles include the rules of the cluster roles they select
+func (g *Graph) RoleRules(ref ObjectRef) []rbac.PolicyRule {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.roleRules(ref, map[ObjectRef]bool{})
+}
+
+// roleRules must be called with the lock held
+func (g *Graph) roleRules(ref ObjectRef, visited map[ObjectRef]bool) []rbac.PolicyRule {
+	role, ok := g.roles[ref]
+	if !ok || visited[ref] {
+		return nil
+	}
+	visited[ref] = true
+	rules := slices.Clone(role.Rules)
+	if role.AggregationRule == nil {
+		return rules
+	}
+	for _, aggregated := range g.aggregatedRoles(role) {
+		for _, rule := range g.roleRules(aggregated, visited) {
+			if !slices.ContainsFunc(rules, func(r rbac.PolicyRule) bool { return reflect.DeepEqual(r, rule) }) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// aggregatedRoles returns the sorted cluster roles selected by the aggregation rule of a cluster role
+func (g *Graph) aggregatedRoles(role *Role) []ObjectRef {
+	selectors := make([]labels.Selector, 0, len(role.AggregationRule.ClusterRoleSelectors))
+	for i := range role.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&role.AggregationRule.ClusterRoleSelectors[i])
+		if err != nil || selector.Empty() {
+			continue
+		}
+		selectors = append(selectors, selector)
+	}
+	refs := make([]ObjectRef, 0)
+	for ref, candidate := range g.roles {
+		if ref.Kind != KindClusterRole || ref == role.ObjectRef {
+			continue
+		}
+		for _, selector := range selectors {
+			if selector.Matches(labels.Set(candidate.Labels)) {
+				refs = append(refs, ref)
+				break
+			}
+		}
+	}
+	sortRefs(refs)
+	return refs
+}
+
+// SubjectRules returns the rules granted to a subject, e.g. "what can service account X in namespace Y do".
+// Service accounts also get the rules granted to their implicit groups.
+func (g *Graph) SubjectRules(subject rbac.Subject) []Grant {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	grants := make([]Grant, 0)
+	for _, key := range subjectKeys(subject) {
+		for _, ref := range sortedKeys(g.bindingsBySubject[key]) {
+			binding := g.bindings[ref]
+			for _, bindingSubject := range binding.Subjects {
+				if keyOfSubject(bindingSubject, binding.Namespace) != key {
+					continue
+				}
+				grants = append(grants, g.bindingGrants(binding, bindingSubject)...)
+			}
+		}
+	}
+	return grants
+}
+
+// WhoCan returns the grants allowing the request, e.g. "who can get secrets in namespace Z"
+func (g *Graph) WhoCan(request Request) []Grant {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	grants := make([]Grant, 0)
+	for _, ref := range sortedKeys(g.bindings) {
+		binding := g.bindings[ref]
+		// a role binding grants access only in its namespace
+		if binding.Kind == KindRoleBinding && binding.Namespace != request.Namespace {
+			continue
+		}
+		for _, rule := range g.roleRules(binding.Role(), map[ObjectRef]bool{}) {
+			if !RuleAllows(rule, request) {
+				continue
+			}
+			for _, subject := range binding.Subjects {
+				grants = append(grants, Grant{Binding: ref, Role: binding.Role(), Subject: subject, Rule: rule, Namespace: binding.Namespace})
+			}
+		}
+	}
+	return grants
+}
+
+// BindingsForRole returns the bindings referencing a role, e.g. "which bindings grant cluster-admin"
+func (g *Graph) BindingsForRole(ref ObjectRef) []*Binding {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	bindings := make([]*Binding, 0)
+	for _, bindingRef := range sortedKeys(g.bindingsByRole[ref]) {
+		bindings = append(bindings, g.bindings[bindingRef])
+	}
+	return bindings
+}
+
+// bindingGrants must be called with the lock held
+func (g *Graph) bindingGrants(binding *Binding, subject rbac.Subject) []Grant {
+	rules := g.roleRules(binding.Role(), map[ObjectRef]bool{})
+	grants := make([]Grant, 0, len(rules))
+	for _, rule := range rules {
+		grants = append(grants, Grant{Binding: binding.ObjectRef, Role: binding.Role(), Subject: subject, Rule: rule, Namespace: binding.Namespace})
+	}
+	return grants
+}
+
+// RuleAllows returns true if the rule allows the request, following the Kubernetes RBAC authorizer matching
+func RuleAllows(rule rbac.PolicyRule, request Request) bool {
+	if !matches(rule.Verbs, request.Verb) {
+		return false
+	}
+	if request.NonResourceURL != "" {
+		for _, url := range rule.NonResourceURLs {
+			if url == rbac.NonResourceAll || url == request.NonResourceURL ||
+				(strings.HasSuffix(url, "*") && strings.HasPrefix(request.NonResourceURL, strings.TrimSuffix(url, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+	if !matches(rule.APIGroups, request.APIGroup) || !resourceMatches(rule.Resources, request.Resource) {
+		return false
+	}
+	return len(rule.ResourceNames) == 0 || (request.ResourceName != "" && slices.Contains(rule.ResourceNames, request.ResourceName))
+}
+
+func matches(values []string, value string) bool {
+	return slices.Contains(values, rbac.VerbAll) || slices.Contains(values, value)
+}
+
+// resourceMatches matches a resource with an optional subresource, "*/subresource" matches the subresource of all resources
+func resourceMatches(resources []string, resource string) bool {
+	for _, r := range resources {
+		if r == rbac.ResourceAll || r == resource {
+			return true
+		}
+		if _, subresource, ok := strings.Cut(resource, "/"); ok && r == "*/"+subresource {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[ObjectRef]V) []ObjectRef {
+	refs := make([]ObjectRef, 0, len(m))
+	for ref := range m {
+		refs = append(refs, ref)
+	}
+	sortRefs(refs)
+	return refs
+}
+
+func sortRefs(refs []ObjectRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+}
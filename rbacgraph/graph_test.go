@@ -0,0 +1,118 @@
+package rbacgraph
+
+import (
+	"testing"
+
+	"github.com/armosec/utils-k8s-go/armometadata"
+	"github.com/stretchr/testify/assert"
+	rbac "k8s.io/api/rbac/v1"
+)
+
+func extract(t *testing.T, object string) *armometadata.Metadata {
+	m, err := armometadata.ExtractMetadataFromJsonBytes([]byte(object))
+	assert.NoError(t, err)
+	return &m
+}
+
+func newTestGraph(t *testing.T) *Graph {
+	g := NewGraph()
+	for _, object := range []string{
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"cluster-admin"},
+			"rules":[{"apiGroups":["*"],"resources":["*"],"verbs":["*"]},{"nonResourceURLs":["*"],"verbs":["*"]}]}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"monitoring"},
+			"aggregationRule":{"clusterRoleSelectors":[{"matchLabels":{"aggregate-to-monitoring":"true"}}]}}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"pod-reader","labels":{"aggregate-to-monitoring":"true"}},
+			"rules":[{"apiGroups":[""],"resources":["pods","pods/log"],"verbs":["get","list"]}]}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"Role","metadata":{"name":"secret-reader","namespace":"prod"},
+			"rules":[{"apiGroups":[""],"resources":["secrets"],"verbs":["get"],"resourceNames":["db"]}]}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"app-secrets","namespace":"prod"},
+			"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"Role","name":"secret-reader"},
+			"subjects":[{"kind":"ServiceAccount","name":"app"}]}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"monitoring","namespace":"prod"},
+			"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"monitoring"},
+			"subjects":[{"kind":"Group","name":"system:serviceaccounts:prod"}]}`,
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRoleBinding","metadata":{"name":"admins"},
+			"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"cluster-admin"},
+			"subjects":[{"kind":"User","name":"alice"},{"kind":"ServiceAccount","name":"operator","namespace":"kube-system"}]}`,
+		`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"app","namespace":"prod"}}`,
+	} {
+		assert.NoError(t, g.Add(extract(t, object)))
+	}
+	return g
+}
+
+func grantRoles(grants []Grant) []string {
+	roles := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		roles = append(roles, grant.Role.String())
+	}
+	return roles
+}
+
+func TestSubjectRules(t *testing.T) {
+	g := newTestGraph(t)
+	assert.True(t, g.HasServiceAccount("prod", "app"))
+
+	grants := g.SubjectRules(rbac.Subject{Kind: rbac.ServiceAccountKind, Namespace: "prod", Name: "app"})
+	assert.Equal(t, []string{"Role/prod/secret-reader", "ClusterRole/monitoring"}, grantRoles(grants))
+	assert.Equal(t, "prod", grants[1].Namespace)
+	assert.Equal(t, []string{"pods", "pods/log"}, grants[1].Rule.Resources)
+
+	// the same service account name in another namespace has no grants
+	assert.Empty(t, g.SubjectRules(rbac.Subject{Kind: rbac.ServiceAccountKind, Namespace: "dev", Name: "app"}))
+
+	grants = g.SubjectRules(rbac.Subject{Kind: rbac.UserKind, Name: "alice"})
+	assert.Equal(t, []string{"ClusterRole/cluster-admin", "ClusterRole/cluster-admin"}, grantRoles(grants))
+	assert.Equal(t, "", grants[0].Namespace)
+}
+
+func TestWhoCan(t *testing.T) {
+	g := newTestGraph(t)
+
+	grants := g.WhoCan(Request{Verb: "get", Resource: "secrets", ResourceName: "db", Namespace: "prod"})
+	subjects := make([]string, 0)
+	for _, grant := range grants {
+		subjects = append(subjects, grant.Subject.Kind+"/"+grant.Subject.Name)
+	}
+	assert.Equal(t, []string{"User/alice", "ServiceAccount/operator", "ServiceAccount/app"}, subjects)
+
+	// resourceNames rules do not allow list or other names
+	assert.Len(t, g.WhoCan(Request{Verb: "list", Resource: "secrets", Namespace: "prod"}), 2)
+	assert.Len(t, g.WhoCan(Request{Verb: "get", Resource: "secrets", ResourceName: "other", Namespace: "prod"}), 2)
+
+	// aggregated rules, subresources and non-resource urls
+	assert.Len(t, g.WhoCan(Request{Verb: "get", Resource: "pods/log", Namespace: "prod"}), 3)
+	assert.Len(t, g.WhoCan(Request{Verb: "get", Resource: "pods/log", Namespace: "dev"}), 2)
+	assert.Len(t, g.WhoCan(Request{Verb: "get", NonResourceURL: "/metrics"}), 2)
+}
+
+func TestBindingsForRole(t *testing.T) {
+	g := newTestGraph(t)
+	bindings := g.BindingsForRole(ObjectRef{Kind: KindClusterRole, Name: "cluster-admin"})
+	assert.Len(t, bindings, 1)
+	assert.Equal(t, "admins", bindings[0].Name)
+	assert.Empty(t, g.BindingsForRole(ObjectRef{Kind: KindClusterRole, Name: "pod-reader"}))
+}
+
+func TestIncrementalUpdates(t *testing.T) {
+	g := newTestGraph(t)
+	app := rbac.Subject{Kind: rbac.ServiceAccountKind, Namespace: "prod", Name: "app"}
+
+	// the aggregated role loses its rules when the selected role is removed
+	g.Remove(ObjectRef{Kind: KindClusterRole, Name: "pod-reader"})
+	assert.Equal(t, []string{"Role/prod/secret-reader"}, grantRoles(g.SubjectRules(app)))
+
+	// replacing a binding updates the indexes
+	assert.NoError(t, g.Add(extract(t, `{"kind":"RoleBinding","metadata":{"name":"app-secrets","namespace":"prod"},
+		"roleRef":{"kind":"ClusterRole","name":"cluster-admin"},"subjects":[{"kind":"User","name":"bob"}]}`)))
+	assert.Empty(t, g.SubjectRules(app))
+	assert.Len(t, g.BindingsForRole(ObjectRef{Kind: KindClusterRole, Name: "cluster-admin"}), 2)
+	assert.Empty(t, g.BindingsForRole(ObjectRef{Kind: KindRole, Namespace: "prod", Name: "secret-reader"}))
+
+	g.RemoveMetadata(extract(t, `{"kind":"RoleBinding","metadata":{"name":"app-secrets","namespace":"prod"}}`))
+	assert.Nil(t, g.Binding(ObjectRef{Kind: KindRoleBinding, Namespace: "prod", Name: "app-secrets"}))
+	assert.Empty(t, g.SubjectRules(rbac.Subject{Kind: rbac.UserKind, Name: "bob"}))
+
+	assert.Error(t, g.Add(extract(t, `{"kind":"Pod","metadata":{"name":"p","namespace":"prod"}}`)))
+	assert.Error(t, g.Add(extract(t, `{"kind":"ClusterRoleBinding","metadata":{"name":"no-role-ref"}}`)))
+}
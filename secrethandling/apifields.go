@@ -3,7 +3,8 @@ package secrethandling
 import (
 	"bytes"
 	"encoding/binary"
-	"strings"
+
+	"github.com/armosec/utils-k8s-go/wlid"
 )
 
 // API fields
@@ -15,41 +16,18 @@ var (
 	ProjectWlidPrefix    = "project-"
 	SecretSIDPrefix      = "secret-"
 	SubSecretSIDPrefix   = "subsecret-"
-	K8SKindsList         = []string{"ComponentStatus", "ConfigMap", "ControllerRevision", "CronJob",
-		"CustomResourceDefinition", "DaemonSet", "Deployment", "Endpoints", "Event", "HorizontalPodAutoscaler",
-		"Ingress", "Job", "Lease", "LimitRange", "LocalSubjectAccessReview", "MutatingWebhookConfiguration",
-		"Namespace", "NetworkPolicy", "Node", "PersistentVolume", "PersistentVolumeClaim", "Pod",
-		"PodDisruptionBudget", "PodSecurityPolicy", "PodTemplate", "PriorityClass", "ReplicaSet",
-		"ReplicationController", "ResourceQuota", "Role", "RoleBinding", "Secret", "SelfSubjectAccessReview",
-		"SelfSubjectRulesReview", "Service", "ServiceAccount", "StatefulSet", "StorageClass",
-		"SubjectAccessReview", "TokenReview", "ValidatingWebhookConfiguration", "VolumeAttachment"}
-	NativeKindsList = []string{"Dockerized", "Native"}
-	KindReverseMap  = map[string]string{}
+	K8SKindsList         = wlid.K8SKindsList // static seed of wlid.DefaultKindRegistry, see wlid.DefaultKindRegistry.ReverseMap for the registered kinds
+	NativeKindsList      = wlid.NativeKindsList
+	// Deprecated: KindReverseMap is a static snapshot of the seeded kinds, use wlid.DefaultKindRegistry.ReverseMap
+	KindReverseMap = wlid.DefaultKindRegistry.ReverseMap()
 )
 
 // SecretTLVTag the tlv tag
 var SecretTLVTag = []byte{231, 197, 24, 237}
 
-func init() {
-	for _, kind := range K8SKindsList {
-		KindReverseMap[strings.ToLower(strings.Replace(kind, "-", "", -1))] = kind
-	}
-	for _, kind := range NativeKindsList {
-		KindReverseMap[strings.ToLower(strings.Replace(kind, "-", "", -1))] = kind
-	}
-}
-
 // IsKindK8S returns true if kind is a k8s
 func IsKindK8S(k string) bool {
-	if val, ok := KindReverseMap[k]; ok {
-		k = val
-	}
-	for _, k8sKind := range K8SKindsList {
-		if k == k8sKind {
-			return true
-		}
-	}
-	return false
+	return wlid.IsK8SKindInList(k)
 }
 
 // HasSecretTLV is the byte slice an encrypted secret
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/armosec/utils-k8s-go/wlid"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -51,6 +52,27 @@ type PortalDesignator struct {
 	Attributes     map[string]string `json:"attributes"`
 }
 
+// CompileWildWLID compiles the designator wild wlid, a designator with a plain wlid matches only that workload
+func (designator *PortalDesignator) CompileWildWLID() (*wlid.WildWLID, error) {
+	if designator.WildWLID != "" {
+		return wlid.CompileWildWLID(designator.WildWLID)
+	}
+	if designator.WLID != "" {
+		return wlid.CompileWildWLID(designator.WLID)
+	}
+	return nil, fmt.Errorf("designator has no wlid or wildwlid")
+}
+
+// ContainsWLID returns true if the designator wlid/wildwlid contains the given wlid.
+// The pattern is compiled on each call, use CompileWildWLID once to match many wlids.
+func (designator *PortalDesignator) ContainsWLID(w string) (bool, error) {
+	matcher, err := designator.CompileWildWLID()
+	if err != nil {
+		return false, err
+	}
+	return matcher.MatchString(w), nil
+}
+
 // SecretAccessPolicy represent list od workloads allows to access some secrets
 // Notice that in K8S, workload can use secret only in case they are in the same namespace
 type SecretAccessPolicy struct {
@@ -176,10 +198,16 @@ func GetSubSecretFromAnnotation(annotationTag string) string {
 
 // GetID returnd the sid of the secret
 func (sec *K8SSecret) GetID() string {
-	return fmt.Sprintf("sid://cluster-%s/namespace-%s/secret-%s", sec.CAClusterName, sec.Namespace, sec.Name)
+	return sec.SID().String()
+}
+
+// SID returns the parsed sid of the secret
+func (sec *K8SSecret) SID() SID {
+	return NewK8sSID(sec.CAClusterName, sec.Namespace, sec.Name, "")
 }
 
-// SplitSecretID splits the secret id string into cluster, namespace, secret-name [,sub-secret-name]
+// SplitSecretID splits the secret id string into cluster, namespace, secret-name [,sub-secret-name].
+// The components of EncodingV1 sids (e.g. "sid+v1://cluster-gke%2Fprod/...") are decoded, legacy components are kept as is.
 func SplitSecretID(sid string) ([]string, error) {
 	if err := ValidateSecretID(sid); err != nil {
 		return nil, err
@@ -211,6 +239,10 @@ func SplitSecretID(sid string) ([]string, error) {
 	if len(splits) > 5 {
 		rslt = append(rslt, splits[5][len(SubSecretSIDPrefix):])
 	}
+	enc := wlid.DetectEncoding(sid)
+	for i := range rslt {
+		rslt[i] = enc.Unescape(rslt[i])
+	}
 	return rslt, nil
 }
 
@@ -260,20 +292,22 @@ func ValidateSecretID(sid string) error {
 	return nil
 }
 
-// GetSID get secret is
+// GetSID get secret is, the components are escaped if needed (see wlid.ComponentsEncoding)
 func GetSID(cluster, namespace, name, subsecret string) string {
-	sid := fmt.Sprintf("sid://%s%s/%s%s/secret-%s", ClusterWlidPrefix, cluster, NamespaceWlidPrefix, namespace, name)
-	if subsecret != "" {
-		sid = fmt.Sprintf("%s/subsecret-%s", sid, subsecret)
-	}
-	return sid
+	return generateSID(ClusterWlidPrefix, cluster, NamespaceWlidPrefix, namespace, name, subsecret)
 }
 
-// GetNativeSID get native secret is
+// GetNativeSID get native secret is, the components are escaped if needed (see wlid.ComponentsEncoding)
 func GetNativeSID(datacenter, project, name, subsecret string) string {
-	sid := fmt.Sprintf("sid://%s%s/%s%s/secret-%s", DataCenterWlidPrefix, datacenter, ProjectWlidPrefix, project, name)
+	return generateSID(DataCenterWlidPrefix, datacenter, ProjectWlidPrefix, project, name, subsecret)
+}
+
+func generateSID(pLevel0, level0, pLevel1, level1, name, subsecret string) string {
+	enc := wlid.ComponentsEncoding(level0, level1, name, subsecret)
+	sid := fmt.Sprintf("%s%s%s/%s%s/%s%s", enc.Prefix(wlid.SidPrefix), pLevel0, enc.Escape(level0), pLevel1, enc.Escape(level1),
+		SecretSIDPrefix, enc.Escape(name))
 	if subsecret != "" {
-		sid = fmt.Sprintf("%s/subsecret-%s", sid, subsecret)
+		sid = fmt.Sprintf("%s/%s%s", sid, SubSecretSIDPrefix, enc.Escape(subsecret))
 	}
 	return sid
 }
@@ -281,61 +315,55 @@ func GetNativeSID(datacenter, project, name, subsecret string) string {
 // IsSIDK8s get secret kind
 func IsSIDK8s(sid string) bool {
 	splits := strings.Split(sid, "/")
-	if sid == "sid://" || strings.HasPrefix(splits[2], ClusterWlidPrefix) {
+	if sid == "sid://" || (len(splits) > 2 && strings.HasPrefix(splits[2], ClusterWlidPrefix)) {
 		return true
 	}
 	return false
 }
 
-// GetSIDCluster get cluster name from secret-id
+// GetSIDCluster get cluster name from secret-id, empty if the sid is invalid
 func GetSIDCluster(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	return splitted[0]
+	s, _ := ParseSID(sid)
+	return s.Level0
 }
 
-// GetSIDNamespace get namespace name from secret-id
+// GetSIDNamespace get namespace name from secret-id, empty if the sid is invalid
 func GetSIDNamespace(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	return splitted[1]
+	s, _ := ParseSID(sid)
+	return s.Level1
 }
 
-// GetSIDLevel0 get level0 name from secret-id
+// GetSIDLevel0 get level0 name from secret-id, empty if the sid is invalid
 func GetSIDLevel0(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	return splitted[0]
+	s, _ := ParseSID(sid)
+	return s.Level0
 }
 
-// GetSIDLevel1 get level1 name from secret-id
+// GetSIDLevel1 get level1 name from secret-id, empty if the sid is invalid
 func GetSIDLevel1(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	return splitted[1]
+	s, _ := ParseSID(sid)
+	return s.Level1
 }
 
-// GetSIDName get secret name from secret-id
+// GetSIDName get secret name from secret-id, empty if the sid is invalid
 func GetSIDName(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	return splitted[2]
+	s, _ := ParseSID(sid)
+	return s.Name
 }
 
 // GetSIDSubsecret get subsecret name from secret-id, if not found, return empty string
 func GetSIDSubsecret(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	if len(splitted) > 3 {
-		return splitted[3]
-	}
-	return ""
+	s, _ := ParseSID(sid)
+	return s.Subsecret
 }
 
 // RemoveSIDSubsecret get subsecret name from secret-id, if not found, return empty string
 func RemoveSIDSubsecret(sid string) string {
-	splitted, _ := SplitSecretID(sid)
-	if len(splitted) < 3 {
+	s, err := ParseSID(sid)
+	if err != nil {
 		return ""
 	}
-	if IsSIDK8s(sid) {
-		return GetSID(splitted[0], splitted[1], splitted[2], "")
-	}
-	return GetNativeSID(splitted[0], splitted[1], splitted[2], "")
+	return s.WithoutSubsecret().String()
 }
 
 // GetSecretIDsFromPolicyList list secret-ids from a list of policies
@@ -376,43 +404,19 @@ func IsSecretTypeSupported(secretType corev1.SecretType) bool {
 
 // GenerateDefaultNamespacePolicy generate default secret access policy based on namespace
 func GenerateDefaultNamespacePolicy(sid string) *SecretAccessPolicy {
-
-	keyLevel0 := ""
-	keyLevel1 := ""
-
-	if IsSIDK8s(sid) {
-		keyLevel0 = strings.TrimSuffix(ClusterWlidPrefix, "-")
-		keyLevel1 = strings.TrimSuffix(NamespaceWlidPrefix, "-")
-	} else {
-		keyLevel0 = strings.TrimSuffix(DataCenterWlidPrefix, "-")
-		keyLevel1 = strings.TrimSuffix(ProjectWlidPrefix, "-")
-	}
-	return &SecretAccessPolicy{
-		PortalBase: PortalBase{
-			Name: sid,
-			Attributes: map[string]interface{}{
-				"name":   "generatedInBackend",
-				"policy": "generatedInBackend",
-			},
-		},
-		CreationDate: time.Now().UTC().Format(time.RFC3339),
-		PolicyType:   "secretAccessList",
-		Designators: []PortalDesignator{
-			{
-				DesignatorType: "attributes",
-				Attributes: map[string]string{
-					keyLevel0: GetSIDLevel0(sid),
-					keyLevel1: GetSIDLevel1(sid),
-				},
-			},
-		},
-		Secrets: []PortalSecretDefinition{
-			{
-				SecretID: sid,
-				KeyIDs:   []PortalSubSecretDefinition{},
-			},
-		},
+	s, err := ParseSID(sid)
+	if err != nil {
+		// keep the level types, the levels of an invalid sid are empty
+		if IsSIDK8s(sid) {
+			s = NewK8sSID("", "", "", "")
+		} else {
+			s = NewNativeSID("", "", "", "")
+		}
 	}
+	policy := s.DefaultNamespacePolicy()
+	policy.Name = sid
+	policy.Secrets[0].SecretID = sid
+	return policy
 }
 
 // EditEncryptionSecretPolicy remove subsecret name from sid
@@ -421,23 +425,22 @@ func EditEncryptionSecretPolicy(secretAccessPolicy *SecretAccessPolicy) {
 		return
 	}
 	for i := range secretAccessPolicy.Secrets {
-		sid := secretAccessPolicy.Secrets[i].SecretID
 		if secretAccessPolicy.Secrets[i].KeyIDs == nil {
 			secretAccessPolicy.Secrets[i].KeyIDs = []PortalSubSecretDefinition{}
 		}
-		subsecret := GetSIDSubsecret(sid)
-		if subsecret == "" {
+		sid, err := secretAccessPolicy.Secrets[i].SID()
+		if err != nil || sid.Subsecret == "" {
 			continue
 		}
-		secretAccessPolicy.Secrets[i].SecretID = RemoveSIDSubsecret(sid)
+		secretAccessPolicy.Secrets[i].SecretID = sid.WithoutSubsecret().String()
 		found := false
 		for j := range secretAccessPolicy.Secrets[i].KeyIDs {
-			if secretAccessPolicy.Secrets[i].KeyIDs[j].SubSecretName == subsecret {
+			if secretAccessPolicy.Secrets[i].KeyIDs[j].SubSecretName == sid.Subsecret {
 				found = true
 			}
 		}
 		if len(secretAccessPolicy.Secrets[i].KeyIDs) == 0 || !found {
-			secretAccessPolicy.Secrets[i].KeyIDs = append(secretAccessPolicy.Secrets[i].KeyIDs, PortalSubSecretDefinition{SubSecretName: subsecret})
+			secretAccessPolicy.Secrets[i].KeyIDs = append(secretAccessPolicy.Secrets[i].KeyIDs, PortalSubSecretDefinition{SubSecretName: sid.Subsecret})
 		}
 	}
 
@@ -1,6 +1,10 @@
 package secrethandling
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/armosec/utils-k8s-go/wlid"
+)
 
 func TestIsSecretTypeSupported(t *testing.T) {
 	if !IsSecretTypeSupported("Opaque") {
@@ -100,3 +104,64 @@ func TestIsSIDK8s(t *testing.T) {
 	}
 
 }
+
+func TestSIDEscaping(t *testing.T) {
+	sid := GetSID("gke/prod", "default", "db creds", "pass%word")
+	if sid != "sid+v1://cluster-gke%2Fprod/namespace-default/secret-db%20creds/subsecret-pass%25word" {
+		t.Errorf("unexpected sid %s", sid)
+	}
+	if err := ValidateSecretID(sid); err != nil {
+		t.Error(err)
+	}
+	splitted, err := SplitSecretID(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if splitted[0] != "gke/prod" || splitted[1] != "default" || splitted[2] != "db creds" || splitted[3] != "pass%word" {
+		t.Errorf("unexpected split %v", splitted)
+	}
+	if s := RemoveSIDSubsecret(sid); s != "sid+v1://cluster-gke%2Fprod/namespace-default/secret-db%20creds" {
+		t.Errorf("unexpected sid %s", s)
+	}
+
+	// legacy sids are not escaped nor decoded
+	if name := GetSIDName("sid://cluster-c/namespace-ns/secret-50%"); name != "50%" {
+		t.Errorf("unexpected name %s", name)
+	}
+	if name := GetSIDName("sid://cluster-c/namespace-ns/secret-a%2Fb"); name != "a%2Fb" {
+		t.Errorf("unexpected name %s", name)
+	}
+	if sid := GetSID("c", "ns", "50%", ""); sid != "sid://cluster-c/namespace-ns/secret-50%" {
+		t.Errorf("unexpected sid %s", sid)
+	}
+}
+
+func TestPortalDesignatorContainsWLID(t *testing.T) {
+	designator := PortalDesignator{WildWLID: "wlid://cluster-prod-*/namespace-default"}
+	contains, err := designator.ContainsWLID("wlid://cluster-prod-eu/namespace-default/deployment-web")
+	if err != nil || !contains {
+		t.Errorf("expected the wildwlid to contain the wlid, err: %v", err)
+	}
+	contains, err = designator.ContainsWLID("wlid://cluster-dev/namespace-default/deployment-web")
+	if err != nil || contains {
+		t.Errorf("expected the wildwlid not to contain the wlid, err: %v", err)
+	}
+
+	designator = PortalDesignator{WildWLID: "wlid://cluster-[prod/namespace-default"}
+	if _, err := designator.ContainsWLID("wlid://cluster-prod/namespace-default/deployment-web"); err == nil {
+		t.Errorf("expected an invalid pattern error")
+	}
+	if _, err := (&PortalDesignator{}).ContainsWLID("wlid://cluster-prod/namespace-default/deployment-web"); err == nil {
+		t.Errorf("expected an error for a designator without wlid")
+	}
+}
+
+func TestKindReverseMap(t *testing.T) {
+	if kind := KindReverseMap["statefulset"]; kind != "StatefulSet" {
+		t.Errorf("unexpected kind %s", kind)
+	}
+	wlid.RegisterKinds("SealedSecret")
+	if kind := wlid.DefaultKindRegistry.ReverseMap()["sealedsecret"]; kind != "SealedSecret" {
+		t.Errorf("expected the kinds registered at runtime, found %s", kind)
+	}
+}
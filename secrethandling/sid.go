@@ -0,0 +1,196 @@
+package secrethandling
+
+import (
+	"encoding"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	_ encoding.TextMarshaler   = SID{}
+	_ encoding.TextUnmarshaler = (*SID)(nil)
+)
+
+// SID is a parsed secret identifier, e.g. "sid://cluster-c/namespace-ns/secret-name/subsecret-key".
+// The zero value is an empty identifier and is rendered as an empty string.
+type SID struct {
+	// cluster/datacenter
+	Level0     string
+	Level0Type string
+
+	// namespace/project
+	Level1     string
+	Level1Type string
+
+	Name string
+	// Subsecret is the key of the secret data, empty for the whole secret
+	Subsecret string
+}
+
+// NewK8sSID returns a k8s SID (cluster/namespace)
+func NewK8sSID(cluster, namespace, name, subsecret string) SID {
+	return SID{
+		Level0:     cluster,
+		Level0Type: strings.TrimSuffix(ClusterWlidPrefix, "-"),
+		Level1:     namespace,
+		Level1Type: strings.TrimSuffix(NamespaceWlidPrefix, "-"),
+		Name:       name,
+		Subsecret:  subsecret,
+	}
+}
+
+// NewNativeSID returns a native SID (datacenter/project)
+func NewNativeSID(datacenter, project, name, subsecret string) SID {
+	return SID{
+		Level0:     datacenter,
+		Level0Type: strings.TrimSuffix(DataCenterWlidPrefix, "-"),
+		Level1:     project,
+		Level1Type: strings.TrimSuffix(ProjectWlidPrefix, "-"),
+		Name:       name,
+		Subsecret:  subsecret,
+	}
+}
+
+// ParseSID parses a secret id, the secret name is required and the subsecret is optional
+func ParseSID(s string) (SID, error) {
+	splitted, err := SplitSecretID(s)
+	if err != nil {
+		return SID{}, err
+	}
+	subsecret := ""
+	if len(splitted) > 3 {
+		subsecret = splitted[3]
+	}
+	if IsSIDK8s(s) {
+		return NewK8sSID(splitted[0], splitted[1], splitted[2], subsecret), nil
+	}
+	return NewNativeSID(splitted[0], splitted[1], splitted[2], subsecret), nil
+}
+
+// MustParseSID is like ParseSID but panics if the sid cannot be parsed
+func MustParseSID(s string) SID {
+	sid, err := ParseSID(s)
+	if err != nil {
+		panic(err)
+	}
+	return sid
+}
+
+// String returns the sid string representation
+func (s SID) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return generateSID(s.Level0Type+"-", s.Level0, s.Level1Type+"-", s.Level1, s.Name, s.Subsecret)
+}
+
+// IsZero returns true if the SID is empty
+func (s SID) IsZero() bool {
+	return s == SID{}
+}
+
+// IsK8s returns true if the SID is a cluster/namespace identifier
+func (s SID) IsK8s() bool {
+	return s.Level0Type+"-" == ClusterWlidPrefix
+}
+
+// WithoutSubsecret returns the SID of the whole secret
+func (s SID) WithoutSubsecret() SID {
+	s.Subsecret = ""
+	return s
+}
+
+// WithSubsecret returns the SID of the given subsecret of the secret
+func (s SID) WithSubsecret(subsecret string) SID {
+	s.Subsecret = subsecret
+	return s
+}
+
+// Cluster returns the cluster of a k8s SID
+func (s SID) Cluster() (string, error) {
+	if !s.IsK8s() {
+		return "", fmt.Errorf("sid %s is not a k8s secret id", s.String())
+	}
+	return s.Level0, nil
+}
+
+// Namespace returns the namespace of a k8s SID
+func (s SID) Namespace() (string, error) {
+	if !s.IsK8s() {
+		return "", fmt.Errorf("sid %s is not a k8s secret id", s.String())
+	}
+	return s.Level1, nil
+}
+
+// Datacenter returns the datacenter of a native SID
+func (s SID) Datacenter() (string, error) {
+	if s.Level0Type+"-" != DataCenterWlidPrefix {
+		return "", fmt.Errorf("sid %s is not a native secret id", s.String())
+	}
+	return s.Level0, nil
+}
+
+// Project returns the project of a native SID
+func (s SID) Project() (string, error) {
+	if s.Level1Type+"-" != ProjectWlidPrefix {
+		return "", fmt.Errorf("sid %s is not a native secret id", s.String())
+	}
+	return s.Level1, nil
+}
+
+// DefaultNamespacePolicy returns the default secret access policy of the secret:
+// all workloads of the secret namespace/project may access it
+func (s SID) DefaultNamespacePolicy() *SecretAccessPolicy {
+	sid := s.String()
+	return &SecretAccessPolicy{
+		PortalBase: PortalBase{
+			Name: sid,
+			Attributes: map[string]interface{}{
+				"name":   "generatedInBackend",
+				"policy": "generatedInBackend",
+			},
+		},
+		CreationDate: time.Now().UTC().Format(time.RFC3339),
+		PolicyType:   "secretAccessList",
+		Designators: []PortalDesignator{
+			{
+				DesignatorType: "attributes",
+				Attributes: map[string]string{
+					s.Level0Type: s.Level0,
+					s.Level1Type: s.Level1,
+				},
+			},
+		},
+		Secrets: []PortalSecretDefinition{
+			{
+				SecretID: sid,
+				KeyIDs:   []PortalSubSecretDefinition{},
+			},
+		},
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s SID) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *SID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = SID{}
+		return nil
+	}
+	parsed, err := ParseSID(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// SID parses the secret id of the definition
+func (secretDefinition *PortalSecretDefinition) SID() (SID, error) {
+	return ParseSID(secretDefinition.SecretID)
+}
@@ -0,0 +1,108 @@
+package secrethandling
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSID(t *testing.T) {
+	sid, err := ParseSID("sid://cluster-c/namespace-ns/secret-db/subsecret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sid != NewK8sSID("c", "ns", "db", "password") {
+		t.Errorf("unexpected sid %+v", sid)
+	}
+	if !sid.IsK8s() {
+		t.Errorf("expecting k8s sid")
+	}
+	if s := sid.WithoutSubsecret().String(); s != "sid://cluster-c/namespace-ns/secret-db" {
+		t.Errorf("unexpected sid %s", s)
+	}
+	if cluster, err := sid.Cluster(); err != nil || cluster != "c" {
+		t.Errorf("unexpected cluster %s, %v", cluster, err)
+	}
+	if _, err := sid.Datacenter(); err == nil {
+		t.Errorf("expecting error for the datacenter of a k8s sid")
+	}
+
+	native, err := ParseSID("sid://datacenter-dc/project-p/secret-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if native.IsK8s() || native.String() != "sid://datacenter-dc/project-p/secret-db" {
+		t.Errorf("unexpected sid %+v", native)
+	}
+	if project, err := native.Project(); err != nil || project != "p" {
+		t.Errorf("unexpected project %s, %v", project, err)
+	}
+	if _, err := native.Namespace(); err == nil {
+		t.Errorf("expecting error for the namespace of a native sid")
+	}
+
+	for _, invalid := range []string{"", "sid://", "sid://cluster-c", "sid://cluster-c/namespace-ns", "sid://foo-c/bar-ns/secret-db"} {
+		if _, err := ParseSID(invalid); err == nil {
+			t.Errorf("expecting error for %q", invalid)
+		}
+	}
+}
+
+func TestSIDAccessorsInvalid(t *testing.T) {
+	// invalid sids must not panic
+	if GetSIDCluster("sid://cluster-c") != "" || GetSIDNamespace("invalid") != "" || GetSIDName("") != "" || RemoveSIDSubsecret("sid://") != "" {
+		t.Errorf("expecting empty values for invalid sids")
+	}
+	if IsSIDK8s("sid:/") {
+		t.Errorf("expecting non k8s sid")
+	}
+}
+
+func TestSIDJSON(t *testing.T) {
+	type secret struct {
+		SID SID `json:"sid"`
+	}
+	in := secret{SID: NewK8sSID("c", "ns", "db", "")}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"sid":"sid://cluster-c/namespace-ns/secret-db"}` {
+		t.Errorf("unexpected json %s", b)
+	}
+	out := secret{}
+	if err := json.Unmarshal(b, &out); err != nil || out != in {
+		t.Errorf("unexpected sid %+v, %v", out, err)
+	}
+	if err := json.Unmarshal([]byte(`{"sid":"sid://cluster-c"}`), &out); err == nil {
+		t.Errorf("expecting error")
+	}
+}
+
+func TestSIDPolicies(t *testing.T) {
+	sec := &K8SSecret{}
+	sec.CAClusterName, sec.Namespace, sec.Name = "c", "ns", "db"
+	if sec.GetID() != "sid://cluster-c/namespace-ns/secret-db" {
+		t.Errorf("unexpected id %s", sec.GetID())
+	}
+
+	policy := GenerateDefaultNamespacePolicy("sid://cluster-c/namespace-ns/secret-db")
+	attributes := policy.Designators[0].Attributes
+	if attributes["cluster"] != "c" || attributes["namespace"] != "ns" || policy.Secrets[0].SecretID != "sid://cluster-c/namespace-ns/secret-db" {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	if policy := GenerateDefaultNamespacePolicy("sid://datacenter-dc"); policy.Designators[0].Attributes["datacenter"] != "" {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	policy = &SecretAccessPolicy{Secrets: []PortalSecretDefinition{
+		{SecretID: "sid://cluster-c/namespace-ns/secret-db/subsecret-password"},
+		{SecretID: "invalid"},
+	}}
+	EditEncryptionSecretPolicy(policy)
+	if policy.Secrets[0].SecretID != "sid://cluster-c/namespace-ns/secret-db" || len(policy.Secrets[0].KeyIDs) != 1 || policy.Secrets[0].KeyIDs[0].SubSecretName != "password" {
+		t.Errorf("unexpected secret %+v", policy.Secrets[0])
+	}
+	if policy.Secrets[1].SecretID != "invalid" || policy.Secrets[1].KeyIDs == nil {
+		t.Errorf("unexpected secret %+v", policy.Secrets[1])
+	}
+}
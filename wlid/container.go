@@ -0,0 +1,160 @@
+package wlid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContainerType is the type of a container in a pod spec, the values are the pod spec list keys (initContainers, containers, ephemeralContainers) lowercased and singular
+type ContainerType string
+
+const (
+	InitContainer      ContainerType = "initcontainer"
+	Container          ContainerType = "container"
+	EphemeralContainer ContainerType = "ephemeralcontainer"
+)
+
+// TemplateHashPrefix is the prefix of the optional pod template hash level of a ContainerID
+var TemplateHashPrefix = "template-"
+
+// ContainerID identifies a container of a workload, optionally of a specific pod template (instance).
+// Its string form extends the WLID with the container level and the optional template level, e.g.
+// "wlid://cluster-c/namespace-ns/deployment-nginx/container-nginx/template-5d78c9869d"
+type ContainerID struct {
+	WLID
+	ContainerType ContainerType
+	ContainerName string
+	// TemplateHash is the pod-template-hash label of the pod, empty for workload-level identifiers
+	TemplateHash string
+}
+
+// NewContainerID returns the identifier of a container of the given workload
+func NewContainerID(w WLID, containerType ContainerType, containerName string) ContainerID {
+	return ContainerID{WLID: w, ContainerType: containerType, ContainerName: containerName}
+}
+
+// WithTemplateHash returns a copy of the identifier scoped to the given pod template hash
+func (c ContainerID) WithTemplateHash(hash string) ContainerID {
+	c.TemplateHash = hash
+	return c
+}
+
+// Base returns the WLID of the workload
+func (c ContainerID) Base() WLID {
+	return c.WLID
+}
+
+// ParseContainerID parses a container identifier
+func ParseContainerID(s string) (ContainerID, error) {
+	if StringHasWhitespace(s) {
+		return ContainerID{}, fmt.Errorf("container id %s invalid. whitespace found", s)
+	}
+	rest, enc, ok := TrimPrefix(s, WlidPrefix)
+	if !ok {
+		return ContainerID{}, fmt.Errorf("container id %s invalid. missing %s prefix", s, WlidPrefix)
+	}
+	levels := strings.Split(rest, "/")
+	if len(levels) < 4 || len(levels) > 5 {
+		return ContainerID{}, fmt.Errorf("container id %s invalid. expecting 4 or 5 levels, found %d", s, len(levels))
+	}
+	w, err := Parse(enc.Prefix(WlidPrefix) + strings.Join(levels[:3], "/"))
+	if err != nil {
+		return ContainerID{}, err
+	}
+	c := ContainerID{WLID: w}
+
+	containerType, containerName, _ := strings.Cut(levels[3], "-")
+	switch ContainerType(containerType) {
+	case InitContainer, Container, EphemeralContainer:
+		c.ContainerType = ContainerType(containerType)
+		c.ContainerName = enc.Unescape(containerName)
+	default:
+		return ContainerID{}, fmt.Errorf("container id %s invalid. unknown container type %s", s, containerType)
+	}
+
+	if len(levels) == 5 {
+		if !strings.HasPrefix(levels[4], TemplateHashPrefix) {
+			return ContainerID{}, fmt.Errorf("container id %s invalid. unknown level %s", s, levels[4])
+		}
+		c.TemplateHash = enc.Unescape(levels[4][len(TemplateHashPrefix):])
+	}
+	return c, c.Validate()
+}
+
+// String returns the container identifier string representation
+func (c ContainerID) String() string {
+	if c == (ContainerID{}) {
+		return ""
+	}
+	w := c.WLID
+	enc := wlidEncoding(w.Level0, w.Level1, w.Kind, w.Name, c.ContainerName, c.TemplateHash)
+	wlid, _ := encodeWLID(enc, w.Level0Type+"-", w.Level0, w.Level1Type+"-", w.Level1, w.Kind, w.Name)
+
+	var id strings.Builder
+	id.WriteString(wlid)
+	id.WriteString("/")
+	id.WriteString(string(c.ContainerType))
+	id.WriteString("-")
+	id.WriteString(enc.Escape(c.ContainerName))
+	if c.TemplateHash != "" {
+		id.WriteString("/")
+		id.WriteString(TemplateHashPrefix)
+		id.WriteString(enc.Escape(c.TemplateHash))
+	}
+	return id.String()
+}
+
+// Validate checks that the workload and container levels are set
+func (c ContainerID) Validate() error {
+	if err := c.WLID.Validate(); err != nil {
+		return err
+	}
+	switch c.ContainerType {
+	case InitContainer, Container, EphemeralContainer:
+	default:
+		return fmt.Errorf("invalid container type %q", c.ContainerType)
+	}
+	if c.ContainerName == "" {
+		return fmt.Errorf("empty container name, workload: %s", c.WLID.String())
+	}
+	return nil
+}
+
+// Equal returns true if both identifiers address the same container of the same template
+func (c ContainerID) Equal(other ContainerID) bool {
+	return c.String() == other.String()
+}
+
+// SameContainer returns true if both identifiers address the same container, regardless of the template hash
+func (c ContainerID) SameContainer(other ContainerID) bool {
+	return c.SameWorkload(other.WLID) && c.ContainerType == other.ContainerType && c.ContainerName == other.ContainerName
+}
+
+// SameWorkload returns true if the container belongs to the given workload
+func (c ContainerID) SameWorkload(w WLID) bool {
+	return c.WLID.String() == w.String()
+}
+
+// Compare orders identifiers by their string representation, it returns -1, 0 or +1
+func (c ContainerID) Compare(other ContainerID) int {
+	return strings.Compare(c.String(), other.String())
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c ContainerID) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (c *ContainerID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = ContainerID{}
+		return nil
+	}
+	parsed, err := ParseContainerID(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
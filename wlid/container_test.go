@@ -0,0 +1,75 @@
+package wlid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerID(t *testing.T) {
+	w := NewK8sWLID("c", "ns", "Deployment", "nginx")
+	tests := []struct {
+		name string
+		id   ContainerID
+		want string
+	}{
+		{
+			name: "container",
+			id:   NewContainerID(w, Container, "nginx"),
+			want: "wlid://cluster-c/namespace-ns/deployment-nginx/container-nginx",
+		},
+		{
+			name: "init container with dashes",
+			id:   NewContainerID(w, InitContainer, "wait-for-db"),
+			want: "wlid://cluster-c/namespace-ns/deployment-nginx/initcontainer-wait-for-db",
+		},
+		{
+			name: "ephemeral container of a template",
+			id:   NewContainerID(w, EphemeralContainer, "debugger").WithTemplateHash("5d78c9869d"),
+			want: "wlid://cluster-c/namespace-ns/deployment-nginx/ephemeralcontainer-debugger/template-5d78c9869d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.id.String())
+			parsed, err := ParseContainerID(tt.want)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.id, parsed)
+			assert.Equal(t, w, parsed.Base())
+			assert.True(t, parsed.SameWorkload(w))
+		})
+	}
+}
+
+func TestParseContainerIDErrors(t *testing.T) {
+	for _, id := range []string{
+		"",
+		"wlid://cluster-c/namespace-ns/deployment-nginx",
+		"wlid://cluster-c/namespace-ns/deployment-nginx/sidecar-nginx",
+		"wlid://cluster-c/namespace-ns/deployment-nginx/container-",
+		"wlid://cluster-c/namespace-ns/deployment-nginx/container-nginx/hash-1",
+		"wlid://cluster-c/namespace-ns/container-nginx/template-1",
+	} {
+		_, err := ParseContainerID(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestContainerIDCompare(t *testing.T) {
+	w := NewK8sWLID("c", "ns", "Deployment", "nginx")
+	a := NewContainerID(w, Container, "a")
+	b := NewContainerID(w, Container, "b")
+	assert.Equal(t, -1, a.Compare(b))
+	assert.Equal(t, 0, a.Compare(a))
+	assert.True(t, a.SameContainer(a.WithTemplateHash("1")))
+	assert.False(t, a.Equal(a.WithTemplateHash("1")))
+	assert.False(t, a.SameContainer(b))
+
+	b2, err := json.Marshal(a)
+	assert.NoError(t, err)
+	assert.Equal(t, `"wlid://cluster-c/namespace-ns/deployment-nginx/container-a"`, string(b2))
+	var out ContainerID
+	assert.NoError(t, json.Unmarshal(b2, &out))
+	assert.Equal(t, a, out)
+}
@@ -0,0 +1,166 @@
+package wlid
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoding is the version of the encoding of wlid/sid components
+type Encoding int
+
+const (
+	// EncodingLegacy components are written as is, e.g. "wlid://cluster-c/namespace-ns/deployment-50%"
+	EncodingLegacy Encoding = iota
+	// EncodingV1 components are percent-escaped: '%', '/', whitespace and control characters, and '-' in kinds.
+	// The scheme of the identifier carries EncodingV1Marker, e.g. "wlid+v1://cluster-gke%2Fprod/namespace-ns/deployment-nginx"
+	EncodingV1
+)
+
+// EncodingV1Marker is appended to the scheme of EncodingV1 identifiers
+const EncodingV1Marker = "+v1"
+
+const upperhex = "0123456789ABCDEF"
+
+// DetectEncoding returns the encoding of a wlid/sid from its scheme, identifiers without marker are legacy
+func DetectEncoding(id string) Encoding {
+	scheme, _, found := strings.Cut(id, SpiffePrefix)
+	if found && strings.HasSuffix(scheme, EncodingV1Marker) {
+		return EncodingV1
+	}
+	return EncodingLegacy
+}
+
+// ComponentsEncoding returns the encoding of an identifier of the given components.
+// Identifiers are EncodingV1 only if a component cannot be written as is ('/', whitespace or control characters),
+// so identifiers valid in the legacy encoding are kept byte-for-byte.
+func ComponentsEncoding(components ...string) Encoding {
+	for _, c := range components {
+		if strings.IndexFunc(c, func(r rune) bool { return r != '%' && isReserved(r, false) }) >= 0 {
+			return EncodingV1
+		}
+	}
+	return EncodingLegacy
+}
+
+// TrimPrefix removes the scheme prefix (e.g. WlidPrefix) of the identifier, with or without the encoding marker.
+// ok is false if the identifier has no such prefix.
+func TrimPrefix(id, prefix string) (rest string, enc Encoding, ok bool) {
+	if strings.HasPrefix(id, prefix) {
+		return id[len(prefix):], EncodingLegacy, true
+	}
+	if marked := EncodingV1.Prefix(prefix); strings.HasPrefix(id, marked) {
+		return id[len(marked):], EncodingV1, true
+	}
+	return id, EncodingLegacy, false
+}
+
+// Prefix returns the scheme prefix (e.g. WlidPrefix) of identifiers of the encoding, e.g. "wlid+v1://"
+func (e Encoding) Prefix(prefix string) string {
+	if e != EncodingV1 {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, SpiffePrefix) + EncodingV1Marker + SpiffePrefix
+}
+
+// Escape escapes a component for the encoding, legacy components are returned as is
+func (e Encoding) Escape(s string) string {
+	if e != EncodingV1 {
+		return s
+	}
+	return EscapeComponent(s)
+}
+
+// Unescape decodes a component of the encoding, legacy components are returned as is
+func (e Encoding) Unescape(s string) string {
+	if e != EncodingV1 {
+		return s
+	}
+	return UnescapeComponent(s)
+}
+
+// escapeKind lowercases the kind, EncodingV1 kinds have their reserved characters escaped, including '-'
+func (e Encoding) escapeKind(kind string) string {
+	if e != EncodingV1 {
+		return strings.ToLower(kind)
+	}
+	return escape(strings.ToLower(kind), true)
+}
+
+// EscapeComponent escapes the reserved characters of a wlid/sid component (cluster, namespace, name...) for EncodingV1
+func EscapeComponent(s string) string {
+	return escape(s, false)
+}
+
+func escape(s string, escapeDash bool) string {
+	if !needsEscape(s, escapeDash) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		if !isReserved(r, escapeDash) {
+			b.WriteRune(r)
+			continue
+		}
+		var buf [utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], r)
+		for _, c := range buf[:n] {
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func needsEscape(s string, escapeDash bool) bool {
+	for _, r := range s {
+		if isReserved(r, escapeDash) {
+			return true
+		}
+	}
+	return false
+}
+
+func isReserved(r rune, escapeDash bool) bool {
+	return r == '%' || r == '/' || (escapeDash && r == '-') || unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// UnescapeComponent decodes an escaped wlid/sid component.
+// Legacy components, which are not valid escaped strings (e.g. "50%"), are returned as is.
+func UnescapeComponent(s string) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			buf = append(buf, s[i])
+			continue
+		}
+		if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			return s
+		}
+		buf = append(buf, unhex(s[i+1])<<4|unhex(s[i+2]))
+		i += 2
+	}
+	if !utf8.Valid(buf) {
+		return s
+	}
+	return string(buf)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
@@ -0,0 +1,143 @@
+package wlid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeComponent(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		escaped string
+	}{
+		{name: "plain", in: "kube-system", escaped: "kube-system"},
+		{name: "slash", in: "gke/prod", escaped: "gke%2Fprod"},
+		{name: "percent", in: "50%", escaped: "50%25"},
+		{name: "whitespace", in: "my name\t\n", escaped: "my%20name%09%0A"},
+		{name: "unicode", in: "café  ", escaped: "café%20%C2%A0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.escaped, EscapeComponent(tt.in))
+			assert.Equal(t, tt.in, UnescapeComponent(tt.escaped))
+		})
+	}
+	assert.Equal(t, "foo%2Dbar", EncodingV1.escapeKind("Foo-Bar"))
+	assert.Equal(t, "foo-bar", EncodingLegacy.escapeKind("Foo-Bar"))
+}
+
+func TestEncoding(t *testing.T) {
+	assert.Equal(t, EncodingLegacy, DetectEncoding("wlid://cluster-c/namespace-ns/deployment-a%2Fb"))
+	assert.Equal(t, EncodingV1, DetectEncoding("wlid+v1://cluster-c/namespace-ns/deployment-a%2Fb"))
+	assert.Equal(t, EncodingV1, DetectEncoding("sid+v1://cluster-c/namespace-ns/secret-a%2Fb"))
+
+	assert.Equal(t, EncodingLegacy, ComponentsEncoding("c", "kube-system", "50%"))
+	assert.Equal(t, EncodingV1, ComponentsEncoding("c", "my name"))
+	assert.Equal(t, EncodingV1, ComponentsEncoding("gke/prod"))
+
+	assert.Equal(t, "wlid+v1://", EncodingV1.Prefix(WlidPrefix))
+	assert.Equal(t, WlidPrefix, EncodingLegacy.Prefix(WlidPrefix))
+	rest, enc, ok := TrimPrefix("wlid+v1://cluster-c", WlidPrefix)
+	assert.True(t, ok)
+	assert.Equal(t, EncodingV1, enc)
+	assert.Equal(t, "cluster-c", rest)
+	_, _, ok = TrimPrefix("sid://cluster-c", WlidPrefix)
+	assert.False(t, ok)
+
+	assert.True(t, IsWlid("wlid+v1://cluster-c"))
+	assert.True(t, IsSid("sid+v1://cluster-c"))
+}
+
+func TestUnescapeComponentLegacy(t *testing.T) {
+	assert.Equal(t, "50%", UnescapeComponent("50%"))
+	assert.Equal(t, "%zz", UnescapeComponent("%zz"))
+	assert.Equal(t, "%FF", UnescapeComponent("%FF"))
+	assert.Equal(t, "a-b", UnescapeComponent("a-b"))
+}
+
+func TestEscapedWLID(t *testing.T) {
+	RegisterKinds("Foo-Bar")
+	w := NewK8sWLID("gke/prod", "ns", "Foo-Bar", "my name")
+	s := w.String()
+	assert.Equal(t, "wlid+v1://cluster-gke%2Fprod/namespace-ns/foo%2Dbar-my%20name", s)
+	assert.NoError(t, w.Validate())
+
+	parsed, err := Parse(s)
+	assert.NoError(t, err)
+	assert.Equal(t, w, parsed)
+
+	assert.Equal(t, "gke/prod", GetClusterFromWlid(s))
+	assert.Equal(t, "Foo-Bar", GetKindFromWlid(s))
+	assert.Equal(t, "my name", GetNameFromWlid(s))
+	assert.NoError(t, IsWlidValid(s))
+
+	info, err := SpiffeToSpiffeInfo(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "gke/prod", info.Level0)
+	assert.Equal(t, "my name", info.Name)
+
+	assert.True(t, WildWlidContainsWlid("wlid+v1://cluster-gke%2Fprod/namespace-ns/foo%2Dbar-my%20name", s))
+	assert.True(t, WildWlidContainsWlid("wlid+v1://cluster-gke%2F*/namespace-ns", s))
+	assert.True(t, WildWlidContainsWlid("wlid://cluster-*/namespace-ns/foobar-*", s))
+	assert.False(t, WildWlidContainsWlid("wlid://cluster-gke/namespace-ns", s))
+	// legacy patterns are not decoded
+	assert.False(t, WildWlidContainsWlid("wlid://cluster-gke%2Fprod/namespace-ns", s))
+
+	idx := NewIndex[int]()
+	assert.NoError(t, idx.Insert(w, 1))
+	matched, err := idx.MatchString("wlid+v1://cluster-gke%2Fprod/namespace-ns/foo%2Dbar-*")
+	assert.NoError(t, err)
+	assert.Equal(t, []WLID{w}, matched)
+
+	c := NewContainerID(w, Container, "side car").WithTemplateHash("a/b")
+	assert.Equal(t, s+"/container-side%20car/template-a%2Fb", c.String())
+	parsedContainer, err := ParseContainerID(c.String())
+	assert.NoError(t, err)
+	assert.Equal(t, c, parsedContainer)
+
+	// a container level that cannot be written as is makes the whole container id EncodingV1
+	legacy := MustParse("wlid://cluster-c/namespace-ns/deployment-nginx")
+	c = NewContainerID(legacy, Container, "nginx").WithTemplateHash("a/b")
+	assert.Equal(t, "wlid+v1://cluster-c/namespace-ns/deployment-nginx/container-nginx/template-a%2Fb", c.String())
+	parsedContainer, err = ParseContainerID(c.String())
+	assert.NoError(t, err)
+	assert.Equal(t, c, parsedContainer)
+}
+
+func TestLegacyWLID(t *testing.T) {
+	w, err := Parse("wlid://cluster-c/namespace-ns/deployment-50%")
+	assert.NoError(t, err)
+	assert.Equal(t, "50%", w.Name)
+	assert.Equal(t, "50%", GetNameFromWlid("wlid://cluster-c/namespace-ns/deployment-50%"))
+
+	// legacy wlids are kept byte-for-byte
+	for _, legacy := range []string{
+		"wlid://cluster-gke_prod/namespace-kube-system/deployment-core-dns",
+		"wlid://cluster-c/namespace-ns/deployment-50%",
+		"wlid://cluster-c/namespace-ns/deployment-a%2Fb",
+	} {
+		w, err = Parse(legacy)
+		assert.NoError(t, err)
+		assert.Equal(t, legacy, w.String())
+	}
+	assert.Equal(t, NewK8sWLID("gke_prod", "kube-system", "Deployment", "core-dns"),
+		MustParse("wlid://cluster-gke_prod/namespace-kube-system/deployment-core-dns"))
+
+	// legacy escape sequences are not decoded
+	assert.Equal(t, "a%2Fb", MustParse("wlid://cluster-c/namespace-ns/deployment-a%2Fb").Name)
+	assert.Equal(t, "a%2Fb", GetNameFromWlid("wlid://cluster-c/namespace-ns/deployment-a%2Fb"))
+	info, err := SpiffeToSpiffeInfo("wlid://cluster-c/namespace-ns/deployment-a%2Fb")
+	assert.NoError(t, err)
+	assert.Equal(t, "a%2Fb", info.Name)
+
+	// a '%' is escaped only in EncodingV1 wlids
+	w = NewK8sWLID("gke/prod", "ns", "Deployment", "50%")
+	assert.Equal(t, "wlid+v1://cluster-gke%2Fprod/namespace-ns/deployment-50%25", w.String())
+	assert.Equal(t, w, MustParse(w.String()))
+
+	_, err = Parse("wlid://cluster-c/namespace-ns/deployment-a\tb")
+	assert.Error(t, err)
+	assert.True(t, StringHasWhitespace("a\nb"))
+}
@@ -0,0 +1,256 @@
+package wlid
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Index is a trie of values keyed by WLID levels (cluster/datacenter, namespace/project, kind, name).
+// It accepts both k8s and native WLIDs and is safe for concurrent use.
+type Index[T any] struct {
+	mu   sync.RWMutex
+	root *indexNode[T]
+	size int
+}
+
+type indexNode[T any] struct {
+	levelType string // level type of the node, empty for kind/name nodes
+	value     string // level value of the node
+	children  map[string]*indexNode[T]
+
+	// set on leaves only
+	wlid     WLID
+	data     T
+	hasValue bool
+}
+
+func newIndexNode[T any](levelType, value string) *indexNode[T] {
+	return &indexNode[T]{levelType: levelType, value: value}
+}
+
+// NewIndex returns an empty Index
+func NewIndex[T any]() *Index[T] {
+	return &Index[T]{root: newIndexNode[T]("", "")}
+}
+
+// indexKeys returns the trie keys of a WLID, one per level
+func indexKeys(w WLID) [4][2]string {
+	return [4][2]string{
+		{w.Level0Type, w.Level0},
+		{w.Level1Type, w.Level1},
+		{"", normalizeKind(w.Kind)},
+		{"", w.Name},
+	}
+}
+
+func nodeKey(levelType, value string) string {
+	if levelType == "" {
+		return value
+	}
+	return levelType + "-" + value
+}
+
+// normalizeKind returns the kind as it is compared in wlids, lowercase and without dashes
+func normalizeKind(kind string) string {
+	return strings.ToLower(strings.ReplaceAll(kind, "-", ""))
+}
+
+// Insert adds or replaces the value of a WLID
+func (idx *Index[T]) Insert(w WLID, data T) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	node := idx.root
+	for _, key := range indexKeys(w) {
+		if node.children == nil {
+			node.children = map[string]*indexNode[T]{}
+		}
+		k := nodeKey(key[0], key[1])
+		child, ok := node.children[k]
+		if !ok {
+			child = newIndexNode[T](key[0], key[1])
+			node.children[k] = child
+		}
+		node = child
+	}
+	if !node.hasValue {
+		idx.size++
+	}
+	node.wlid = w
+	node.data = data
+	node.hasValue = true
+	return nil
+}
+
+// InsertString parses the wlid and adds or replaces its value
+func (idx *Index[T]) InsertString(w string, data T) error {
+	parsed, err := Parse(w)
+	if err != nil {
+		return err
+	}
+	if err := idx.Insert(parsed, data); err != nil {
+		return fmt.Errorf("failed to insert %s: %w", w, err)
+	}
+	return nil
+}
+
+// Get returns the value of a WLID
+func (idx *Index[T]) Get(w WLID) (T, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	node := idx.root
+	for _, key := range indexKeys(w) {
+		child, ok := node.children[nodeKey(key[0], key[1])]
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		node = child
+	}
+	return node.data, node.hasValue
+}
+
+// Delete removes a WLID from the index, returns true if it was found
+func (idx *Index[T]) Delete(w WLID) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	keys := indexKeys(w)
+	path := make([]*indexNode[T], 0, len(keys)+1)
+	path = append(path, idx.root)
+	node := idx.root
+	for _, key := range keys {
+		child, ok := node.children[nodeKey(key[0], key[1])]
+		if !ok {
+			return false
+		}
+		path = append(path, child)
+		node = child
+	}
+	if !node.hasValue {
+		return false
+	}
+	idx.size--
+
+	// prune the empty branches
+	for i := len(keys) - 1; i >= 0; i-- {
+		if len(path[i+1].children) > 0 {
+			break
+		}
+		delete(path[i].children, nodeKey(keys[i][0], keys[i][1]))
+	}
+	return true
+}
+
+// Len returns the number of WLIDs in the index
+func (idx *Index[T]) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.size
+}
+
+// Range calls fn for every WLID in the index, in no particular order, until fn returns false.
+// fn must not modify the index.
+func (idx *Index[T]) Range(fn func(w WLID, data T) bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.root.walk(nil, 0, fn)
+}
+
+// Match calls fn for every WLID contained in the wild wlid, in no particular order, until fn returns false.
+// fn must not modify the index.
+func (idx *Index[T]) Match(wild *WildWLID, fn func(w WLID, data T) bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.root.walk(wild, 0, fn)
+}
+
+// MatchString compiles the wild wlid and returns all the matching WLIDs
+func (idx *Index[T]) MatchString(wildWlid string) ([]WLID, error) {
+	wild, err := CompileWildWLID(wildWlid)
+	if err != nil {
+		return nil, err
+	}
+	var wlids []WLID
+	idx.Match(wild, func(w WLID, _ T) bool {
+		wlids = append(wlids, w)
+		return true
+	})
+	return wlids, nil
+}
+
+// walk visits the children of the node at the given depth (0 for clusters)
+func (n *indexNode[T]) walk(wild *WildWLID, depth int, fn func(WLID, T) bool) bool {
+	if n.hasValue {
+		return fn(n.wlid, n.data)
+	}
+
+	// use a direct lookup when the level is an exact match
+	if key, ok := wild.exactKey(depth); ok {
+		if child, ok := n.children[key]; ok {
+			return child.walk(wild, depth+1, fn)
+		}
+		return true
+	}
+	for _, child := range n.children {
+		if !wild.matchLevel(depth, child.levelType, child.value) {
+			continue
+		}
+		if !child.walk(wild, depth+1, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// exactKey returns the trie key of the level if it is matched exactly
+func (w *WildWLID) exactKey(depth int) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	switch depth {
+	case 0:
+		return w.level0.exactKey()
+	case 1:
+		return w.level1.exactKey()
+	case 2:
+		if w.kind != nil && w.kind.exact {
+			return w.kind.pattern, true
+		}
+	case 3:
+		if w.name != nil && w.name.exact {
+			return w.name.pattern, true
+		}
+	}
+	return "", false
+}
+
+func (l *levelMatcher) exactKey() (string, bool) {
+	if l == nil || l.levelType == "" || !l.value.exact {
+		return "", false
+	}
+	return nodeKey(l.levelType, l.value.pattern), true
+}
+
+// matchLevel matches a single level, nil or missing levels match everything
+func (w *WildWLID) matchLevel(depth int, levelType, value string) bool {
+	if w == nil {
+		return true
+	}
+	switch depth {
+	case 0:
+		return w.level0 == nil || w.level0.match(levelType, value)
+	case 1:
+		return w.level1 == nil || w.level1.match(levelType, value)
+	case 2:
+		return w.kind == nil || w.kind.match(value)
+	case 3:
+		return w.name == nil || w.name.match(value)
+	}
+	return true
+}
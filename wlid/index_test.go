@@ -0,0 +1,113 @@
+package wlid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestIndex(t *testing.T) *Index[int] {
+	idx := NewIndex[int]()
+	for i, w := range []string{
+		GetK8sWLID("c1", "default", "Deployment", "nginx"),
+		GetK8sWLID("c1", "default", "StatefulSet", "db"),
+		GetK8sWLID("c1", "kube-system", "DaemonSet", "kube-proxy"),
+		GetK8sWLID("c2", "default", "Deployment", "nginx"),
+		GetNativeWLID("dc1", "default", "Dockerized", "nginx"),
+	} {
+		assert.NoError(t, idx.InsertString(w, i))
+	}
+	return idx
+}
+
+func TestIndexInsertGetDelete(t *testing.T) {
+	idx := newTestIndex(t)
+	assert.Equal(t, 5, idx.Len())
+
+	v, ok := idx.Get(NewK8sWLID("c1", "default", "statefulset", "db"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	v, ok = idx.Get(NewNativeWLID("dc1", "default", "Dockerized", "nginx"))
+	assert.True(t, ok)
+	assert.Equal(t, 4, v)
+
+	_, ok = idx.Get(NewK8sWLID("dc1", "default", "Dockerized", "nginx"))
+	assert.False(t, ok)
+
+	// replace
+	assert.NoError(t, idx.Insert(NewK8sWLID("c1", "default", "StatefulSet", "db"), 10))
+	assert.Equal(t, 5, idx.Len())
+	v, _ = idx.Get(NewK8sWLID("c1", "default", "StatefulSet", "db"))
+	assert.Equal(t, 10, v)
+
+	assert.True(t, idx.Delete(NewK8sWLID("c1", "kube-system", "DaemonSet", "kube-proxy")))
+	assert.False(t, idx.Delete(NewK8sWLID("c1", "kube-system", "DaemonSet", "kube-proxy")))
+	assert.Equal(t, 4, idx.Len())
+	assert.NotContains(t, idx.root.children["cluster-c1"].children, "namespace-kube-system")
+
+	assert.Error(t, idx.Insert(NewK8sWLID("c1", "default", "", ""), 0))
+}
+
+func TestIndexMatch(t *testing.T) {
+	idx := newTestIndex(t)
+	tests := []struct {
+		wildWlid string
+		want     []string
+	}{
+		{
+			wildWlid: "wlid://cluster-c1",
+			want: []string{
+				"wlid://cluster-c1/namespace-default/deployment-nginx",
+				"wlid://cluster-c1/namespace-default/statefulset-db",
+				"wlid://cluster-c1/namespace-kube-system/daemonset-kube-proxy",
+			},
+		},
+		{
+			wildWlid: "wlid://cluster-*/namespace-default/deployment",
+			want: []string{
+				"wlid://cluster-c1/namespace-default/deployment-nginx",
+				"wlid://cluster-c2/namespace-default/deployment-nginx",
+			},
+		},
+		{
+			wildWlid: "wlid://*/*/*-nginx",
+			want: []string{
+				"wlid://cluster-c1/namespace-default/deployment-nginx",
+				"wlid://cluster-c2/namespace-default/deployment-nginx",
+				"wlid://datacenter-dc1/project-default/dockerized-nginx",
+			},
+		},
+		{
+			wildWlid: "wlid://cluster-c3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wildWlid, func(t *testing.T) {
+			got, err := idx.MatchString(tt.wildWlid)
+			assert.NoError(t, err)
+			gotStrings := make([]string, 0, len(got))
+			for _, w := range got {
+				gotStrings = append(gotStrings, w.String())
+			}
+			assert.ElementsMatch(t, tt.want, gotStrings)
+		})
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	idx := newTestIndex(t)
+	count := 0
+	idx.Range(func(_ WLID, _ int) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 5, count)
+
+	count = 0
+	idx.Range(func(_ WLID, _ int) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,181 @@
+package wlid
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/discovery"
+)
+
+// KindRegistry is a concurrency-safe set of known kinds.
+// Kinds are looked up case-insensitively and in their wlid form (lowercase without dashes),
+// so a kind restored from a wlid gets its original casing back.
+type KindRegistry struct {
+	mu    sync.RWMutex
+	kinds map[string]registeredKind
+}
+
+type registeredKind struct {
+	kind          string
+	native        bool
+	clusterScoped bool
+}
+
+// ClusterScopedKindsList lists the cluster-scoped kinds of K8SKindsList
+var ClusterScopedKindsList = []string{"ClusterRole", "ClusterRoleBinding", "ComponentStatus", "CustomResourceDefinition",
+	"MutatingWebhookConfiguration", "Namespace", "Node", "PersistentVolume", "PodSecurityPolicy", "PriorityClass",
+	"SelfSubjectAccessReview", "SelfSubjectRulesReview", "StorageClass", "SubjectAccessReview", "TokenReview",
+	"ValidatingWebhookConfiguration", "VolumeAttachment"}
+
+// DefaultKindRegistry is the registry used by the wlid and secrethandling packages,
+// seeded with K8SKindsList, ClusterScopedKindsList and NativeKindsList
+var DefaultKindRegistry = NewKindRegistry(K8SKindsList...)
+
+func init() {
+	DefaultKindRegistry.RegisterClusterScoped(ClusterScopedKindsList...)
+	DefaultKindRegistry.RegisterNative(NativeKindsList...)
+	for k, v := range DefaultKindRegistry.ReverseMap() {
+		KindReverseMap[k] = v
+	}
+}
+
+// NewKindRegistry returns a registry with the given k8s kinds
+func NewKindRegistry(kinds ...string) *KindRegistry {
+	r := &KindRegistry{kinds: make(map[string]registeredKind, len(kinds))}
+	r.Register(kinds...)
+	return r
+}
+
+// Register adds k8s kinds (e.g. CRD kinds) to the registry, kinds already registered as cluster-scoped remain cluster-scoped
+func (r *KindRegistry) Register(kinds ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		r.register(registeredKind{kind: kind, clusterScoped: r.kinds[normalizeKind(kind)].clusterScoped})
+	}
+}
+
+// RegisterClusterScoped adds cluster-scoped k8s kinds to the registry
+func (r *KindRegistry) RegisterClusterScoped(kinds ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		r.register(registeredKind{kind: kind, clusterScoped: true})
+	}
+}
+
+// RegisterNative adds native (non k8s) kinds to the registry
+func (r *KindRegistry) RegisterNative(kinds ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		r.register(registeredKind{kind: kind, native: true})
+	}
+}
+
+// register must be called with the lock held
+func (r *KindRegistry) register(k registeredKind) {
+	if k.kind == "" {
+		return
+	}
+	r.kinds[normalizeKind(k.kind)] = k
+}
+
+// Lookup returns the registered form of a kind
+func (r *KindRegistry) Lookup(kind string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.kinds[normalizeKind(kind)]
+	return k.kind, ok
+}
+
+// IsK8s returns true if the kind is a registered k8s kind
+func (r *KindRegistry) IsK8s(kind string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.kinds[normalizeKind(kind)]
+	return ok && !k.native
+}
+
+// IsClusterScoped returns true if the kind is a registered cluster-scoped kind
+func (r *KindRegistry) IsClusterScoped(kind string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.kinds[normalizeKind(kind)].clusterScoped
+}
+
+// IsNamespaced returns true if the kind is a registered namespaced k8s kind
+func (r *KindRegistry) IsNamespaced(kind string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.kinds[normalizeKind(kind)]
+	return ok && !k.native && !k.clusterScoped
+}
+
+// Kinds returns the sorted list of the registered kinds
+func (r *KindRegistry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.kinds))
+	for _, k := range r.kinds {
+		kinds = append(kinds, k.kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ReverseMap returns a snapshot of the registered kinds keyed by their wlid form, e.g. "replicaset" -> "ReplicaSet"
+func (r *KindRegistry) ReverseMap() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reverse := make(map[string]string, len(r.kinds))
+	for key, k := range r.kinds {
+		reverse[key] = k.kind
+	}
+	return reverse
+}
+
+// LoadFromDiscovery registers the kinds served by the API server, including CRDs.
+// Groups that failed discovery are skipped, the error is returned after the other kinds are registered.
+func (r *KindRegistry) LoadFromDiscovery(client discovery.ServerResourcesInterface) error {
+	_, resourceLists, err := client.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, resourceList := range resourceLists {
+		if resourceList == nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			// skip subresources, e.g. pods/log
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			r.register(registeredKind{kind: resource.Kind, clusterScoped: !resource.Namespaced})
+		}
+	}
+	return err
+}
+
+// RegisterKinds adds k8s kinds (e.g. CRD kinds) to the DefaultKindRegistry
+func RegisterKinds(kinds ...string) {
+	DefaultKindRegistry.Register(kinds...)
+}
+
+// RegisterClusterScopedKinds adds cluster-scoped k8s kinds to the DefaultKindRegistry
+func RegisterClusterScopedKinds(kinds ...string) {
+	DefaultKindRegistry.RegisterClusterScoped(kinds...)
+}
+
+// IsClusterScopedKind returns true if the kind is registered as cluster-scoped in the DefaultKindRegistry
+func IsClusterScopedKind(kind string) bool {
+	return DefaultKindRegistry.IsClusterScoped(kind)
+}
+
+// LoadKindsFromDiscovery registers the kinds served by the API server in the DefaultKindRegistry
+func LoadKindsFromDiscovery(client discovery.ServerResourcesInterface) error {
+	return DefaultKindRegistry.LoadFromDiscovery(client)
+}
@@ -0,0 +1,69 @@
+package wlid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestKindRegistry(t *testing.T) {
+	r := NewKindRegistry(K8SKindsList...)
+	r.RegisterNative(NativeKindsList...)
+
+	k, ok := r.Lookup("replicaset")
+	assert.True(t, ok)
+	assert.Equal(t, "ReplicaSet", k)
+	assert.True(t, r.IsK8s("REPLICASET"))
+	assert.False(t, r.IsK8s("Dockerized"))
+	k, _ = r.Lookup("dockerized")
+	assert.Equal(t, "Dockerized", k)
+
+	_, ok = r.Lookup("cronworkflow")
+	assert.False(t, ok)
+	r.Register("CronWorkflow")
+	k, ok = r.Lookup("cronworkflow")
+	assert.True(t, ok)
+	assert.Equal(t, "CronWorkflow", k)
+	assert.Contains(t, r.Kinds(), "CronWorkflow")
+	assert.Equal(t, "CronWorkflow", r.ReverseMap()["cronworkflow"])
+	assert.Equal(t, "ReplicaSet", r.ReverseMap()["replicaset"])
+
+	// the deprecated map is a snapshot of the seeded kinds
+	assert.Equal(t, "ReplicaSet", KindReverseMap["replicaset"])
+	assert.Equal(t, "Dockerized", KindReverseMap["dockerized"])
+}
+
+func TestKindRegistryLoadFromDiscovery(t *testing.T) {
+	client := &fake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "argoproj.io/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "rollouts", Kind: "Rollout", Namespaced: true},
+				{Name: "rollouts/status", Kind: "Rollout", Namespaced: true},
+				{Name: "cronworkflows", Kind: "CronWorkflow", Namespaced: true},
+			},
+		},
+	}
+	r := NewKindRegistry()
+	assert.NoError(t, r.LoadFromDiscovery(client))
+	assert.Equal(t, []string{"CronWorkflow", "Rollout"}, r.Kinds())
+}
+
+func TestRegisteredKindIsRestored(t *testing.T) {
+	const w = "wlid://cluster-c/namespace-ns/rollout-web"
+	RegisterKinds("Rollout")
+	defer func() {
+		DefaultKindRegistry.mu.Lock()
+		delete(DefaultKindRegistry.kinds, "rollout")
+		DefaultKindRegistry.mu.Unlock()
+	}()
+
+	assert.Equal(t, []string{"c", "ns", "Rollout", "web"}, RestoreMicroserviceIDs(w))
+	assert.Equal(t, "Rollout", GetKindFromWlid(w))
+	assert.True(t, IsK8SKindInList("rollout"))
+	assert.Equal(t, "Rollout", MustParse(w).Kind)
+}
@@ -0,0 +1,189 @@
+package wlid
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// globMatcher matches a single wlid component against a precompiled pattern.
+// Exact patterns are stored unescaped, glob patterns are matched against the component escaped for the pattern encoding.
+type globMatcher struct {
+	pattern string
+	any     bool
+	exact   bool
+	enc     Encoding
+}
+
+func compileGlob(pattern string, enc Encoding) (globMatcher, error) {
+	if pattern == "*" {
+		return globMatcher{pattern: pattern, any: true}, nil
+	}
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		return globMatcher{pattern: enc.Unescape(pattern), exact: true}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return globMatcher{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return globMatcher{pattern: pattern, enc: enc}, nil
+}
+
+func (g globMatcher) match(s string) bool {
+	switch {
+	case g.any:
+		return true
+	case g.exact:
+		return g.pattern == s
+	}
+	matched, _ := path.Match(g.pattern, g.enc.Escape(s))
+	return matched
+}
+
+// levelMatcher matches a "<type>-<value>" level, e.g. "cluster-prod-*"
+type levelMatcher struct {
+	levelType string // empty matches any type
+	value     globMatcher
+}
+
+func (l levelMatcher) match(levelType, value string) bool {
+	if l.levelType != "" && l.levelType != levelType {
+		return false
+	}
+	// an empty level (cluster-scoped resources) is matched only by an empty pattern or by a whole level wildcard
+	if value == "" && l.value.pattern != "" && !(l.levelType == "" && l.value.any) {
+		return false
+	}
+	return l.value.match(value)
+}
+
+// WildWLID is a compiled wild wlid, safe for concurrent use.
+//
+// Every level may contain glob patterns ('*', '?', '[...]'), e.g. "wlid://cluster-*/namespace-prod-*/deployment-*".
+// Missing levels match everything, so "wlid://cluster-c" matches all the workloads of cluster c and
+// "wlid://cluster-*/namespace-*/deployment" matches all deployments.
+// Cluster-scoped resources are matched by patterns without level1, e.g. "wlid://cluster-*/clusterrole-*",
+// by "wlid://cluster-*/*/..." or by patterns without kind, they are not matched by "namespace-*".
+type WildWLID struct {
+	pattern string
+	level0  *levelMatcher
+	level1  *levelMatcher
+	kind    *globMatcher
+	name    *globMatcher
+}
+
+// CompileWildWLID compiles a wild wlid pattern
+func CompileWildWLID(pattern string) (*WildWLID, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("in CompileWildWLID, expecting valid wild wlid received empty string")
+	}
+	if StringHasWhitespace(pattern) {
+		return nil, fmt.Errorf("wild wlid %s invalid. whitespace found", pattern)
+	}
+	rest, enc, ok := TrimPrefix(pattern, WlidPrefix)
+	if !ok {
+		return nil, fmt.Errorf("wild wlid %s invalid. missing %s prefix", pattern, WlidPrefix)
+	}
+
+	w := &WildWLID{pattern: pattern}
+	levels := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if len(levels) > 3 {
+		return nil, fmt.Errorf("wild wlid %s invalid. expecting up to 3 levels, found %d", pattern, len(levels))
+	}
+
+	var err error
+	if w.level0, err = compileLevel(levels[0], enc, ClusterWlidPrefix, DataCenterWlidPrefix); err != nil {
+		return nil, fmt.Errorf("wild wlid %s invalid: %w", pattern, err)
+	}
+	levels = levels[1:]
+	if len(levels) > 0 {
+		if levels[0] == "*" || strings.HasPrefix(levels[0], NamespaceWlidPrefix) || strings.HasPrefix(levels[0], ProjectWlidPrefix) {
+			if w.level1, err = compileLevel(levels[0], enc, NamespaceWlidPrefix, ProjectWlidPrefix); err != nil {
+				return nil, fmt.Errorf("wild wlid %s invalid: %w", pattern, err)
+			}
+			levels = levels[1:]
+		} else if len(levels) > 1 {
+			return nil, fmt.Errorf("wild wlid %s invalid: unknown level %s", pattern, levels[0])
+		} else {
+			// cluster-scoped resources have an empty level1
+			w.level1 = &levelMatcher{value: globMatcher{exact: true}}
+		}
+	}
+	if len(levels) > 0 {
+		if levels[0] == "" {
+			return nil, fmt.Errorf("wild wlid %s invalid. empty kind", pattern)
+		}
+		kind, name, hasName := strings.Cut(levels[0], "-")
+		kindMatcher, err := compileGlob(strings.ToLower(kind), enc)
+		if err != nil {
+			return nil, fmt.Errorf("wild wlid %s invalid: %w", pattern, err)
+		}
+		if kindMatcher.exact {
+			kindMatcher.pattern = normalizeKind(kindMatcher.pattern)
+		}
+		w.kind = &kindMatcher
+		if hasName {
+			nameMatcher, err := compileGlob(name, enc)
+			if err != nil {
+				return nil, fmt.Errorf("wild wlid %s invalid: %w", pattern, err)
+			}
+			w.name = &nameMatcher
+		}
+	}
+	return w, nil
+}
+
+// MustCompileWildWLID is like CompileWildWLID but panics if the pattern cannot be compiled
+func MustCompileWildWLID(pattern string) *WildWLID {
+	w, err := CompileWildWLID(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func compileLevel(level string, enc Encoding, prefixes ...string) (*levelMatcher, error) {
+	if level == "*" {
+		return &levelMatcher{value: globMatcher{pattern: level, any: true}}, nil
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(level, prefix) {
+			value, err := compileGlob(level[len(prefix):], enc)
+			if err != nil {
+				return nil, err
+			}
+			return &levelMatcher{levelType: strings.TrimSuffix(prefix, "-"), value: value}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown level %s", level)
+}
+
+// String returns the wild wlid pattern
+func (w *WildWLID) String() string {
+	return w.pattern
+}
+
+// Match returns true if the wlid is contained in the wild wlid
+func (w *WildWLID) Match(id WLID) bool {
+	if w.level0 != nil && !w.level0.match(id.Level0Type, id.Level0) {
+		return false
+	}
+	if w.level1 != nil && !w.level1.match(id.Level1Type, id.Level1) {
+		return false
+	}
+	if w.kind != nil && !w.kind.match(normalizeKind(id.Kind)) {
+		return false
+	}
+	if w.name != nil && !w.name.match(id.Name) {
+		return false
+	}
+	return true
+}
+
+// MatchString parses the wlid and returns true if it is contained in the wild wlid
+func (w *WildWLID) MatchString(id string) bool {
+	parsed, err := Parse(id)
+	if err != nil {
+		return false
+	}
+	return w.Match(parsed)
+}
@@ -0,0 +1,141 @@
+package wlid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWildWLIDMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		wildWlid string
+		wlid     string
+		want     bool
+	}{
+		{
+			name:     "exact",
+			wildWlid: "wlid://cluster-c/namespace-ns/deployment-nginx",
+			wlid:     "wlid://cluster-c/namespace-ns/deployment-nginx",
+			want:     true,
+		},
+		{
+			name:     "cluster only",
+			wildWlid: "wlid://cluster-c",
+			wlid:     "wlid://cluster-c/namespace-ns/deployment-nginx",
+			want:     true,
+		},
+		{
+			name:     "other cluster",
+			wildWlid: "wlid://cluster-c/namespace-ns",
+			wlid:     "wlid://cluster-d/namespace-ns/deployment-nginx",
+			want:     false,
+		},
+		{
+			name:     "globs at every level",
+			wildWlid: "wlid://cluster-prod-*/namespace-team-?/deployment-api-*",
+			wlid:     "wlid://cluster-prod-eu/namespace-team-a/deployment-api-gateway",
+			want:     true,
+		},
+		{
+			name:     "glob does not match",
+			wildWlid: "wlid://cluster-prod-*/namespace-team-?/deployment-api-*",
+			wlid:     "wlid://cluster-prod-eu/namespace-team-ab/deployment-api-gateway",
+			want:     false,
+		},
+		{
+			name:     "kind only",
+			wildWlid: "wlid://cluster-*/namespace-*/statefulset",
+			wlid:     "wlid://cluster-c/namespace-ns/statefulset-db",
+			want:     true,
+		},
+		{
+			name:     "kind only other kind",
+			wildWlid: "wlid://cluster-*/namespace-*/statefulset",
+			wlid:     "wlid://cluster-c/namespace-ns/deployment-db",
+			want:     false,
+		},
+		{
+			name:     "any kind",
+			wildWlid: "wlid://cluster-c/namespace-ns/*-db",
+			wlid:     "wlid://cluster-c/namespace-ns/statefulset-db",
+			want:     true,
+		},
+		{
+			name:     "any level type",
+			wildWlid: "wlid://*/project-p",
+			wlid:     "wlid://datacenter-dc/project-p/dockerized-nginx",
+			want:     true,
+		},
+		{
+			name:     "native vs k8s",
+			wildWlid: "wlid://cluster-*",
+			wlid:     "wlid://datacenter-dc/project-p/dockerized-nginx",
+			want:     false,
+		},
+		{
+			name:     "cluster-scoped",
+			wildWlid: "wlid://cluster-*/clusterrole-*",
+			wlid:     "wlid://cluster-c/clusterrole-admin",
+			want:     true,
+		},
+		{
+			name:     "cluster-scoped pattern does not match namespaced",
+			wildWlid: "wlid://cluster-*/role-*",
+			wlid:     "wlid://cluster-c/namespace-ns/role-admin",
+			want:     false,
+		},
+		{
+			name:     "namespace wildcard does not match cluster-scoped",
+			wildWlid: "wlid://cluster-c/namespace-*",
+			wlid:     "wlid://cluster-c/clusterrole-admin",
+			want:     false,
+		},
+		{
+			name:     "level wildcard matches cluster-scoped",
+			wildWlid: "wlid://cluster-c/*/clusterrole",
+			wlid:     "wlid://cluster-c/clusterrole-admin",
+			want:     true,
+		},
+		{
+			name:     "invalid wlid",
+			wildWlid: "wlid://cluster-*",
+			wlid:     "wlid://foo-c/namespace-ns/deployment-nginx",
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := CompileWildWLID(tt.wildWlid)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, w.MatchString(tt.wlid))
+			assert.Equal(t, tt.want, WildWlidContainsWlid(tt.wildWlid, tt.wlid))
+		})
+	}
+}
+
+func TestCompileWildWLIDErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"cluster-c",
+		"wlid://cluster-c/namespace-ns/deployment-nginx/extra",
+		"wlid://foo-c",
+		"wlid://cluster-[",
+	} {
+		_, err := CompileWildWLID(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestWildWlidContainsWlidMalformed(t *testing.T) {
+	assert.False(t, WildWlidContainsWlid("wlid://cluster-c/namespace-ns/deployment-nginx", "wlid://cluster-c"))
+	assert.False(t, WildWlidContainsWlid("wlid://cluster-c/namespace-ns/deployment-nginx/x/y", "wlid://cluster-c/namespace-ns/deployment-nginx"))
+}
+
+func BenchmarkWildWLIDMatch(b *testing.B) {
+	w := MustCompileWildWLID("wlid://cluster-prod-*/namespace-team-*/deployment-*")
+	id := MustParse("wlid://cluster-prod-eu/namespace-team-a/deployment-api-gateway")
+	for i := 0; i < b.N; i++ {
+		_ = w.Match(id)
+	}
+}
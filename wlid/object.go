@@ -0,0 +1,160 @@
+package wlid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// DefaultMaxOwnerDepth is the maximum number of owner references followed by an OwnerResolver
+const DefaultMaxOwnerDepth = 10
+
+// FromObject returns the WLID of a k8s object.
+// The kind is taken from the object TypeMeta, or from the client-go scheme for typed objects with an empty TypeMeta.
+func FromObject(cluster string, obj runtime.Object) (WLID, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return WLID{}, fmt.Errorf("failed to access object metadata: %w", err)
+	}
+	kind, err := objectKind(obj)
+	if err != nil {
+		return WLID{}, err
+	}
+	return NewK8sWLID(cluster, accessor.GetNamespace(), kind, accessor.GetName()), nil
+}
+
+func objectKind(obj runtime.Object) (string, error) {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind, nil
+	}
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to get object kind: %w", err)
+	}
+	if len(gvks) == 0 || gvks[0].Kind == "" {
+		return "", fmt.Errorf("failed to get object kind of %T", obj)
+	}
+	return gvks[0].Kind, nil
+}
+
+// OwnerLookup fetches the owner of an object, it should return a NotFound error if the owner does not exist
+type OwnerLookup interface {
+	GetOwner(ctx context.Context, namespace string, ref metav1.OwnerReference) (metav1.Object, error)
+}
+
+// OwnerLookupFunc is a function implementing OwnerLookup, e.g. on top of informer listers
+type OwnerLookupFunc func(ctx context.Context, namespace string, ref metav1.OwnerReference) (metav1.Object, error)
+
+// GetOwner calls f(ctx, namespace, ref)
+func (f OwnerLookupFunc) GetOwner(ctx context.Context, namespace string, ref metav1.OwnerReference) (metav1.Object, error) {
+	return f(ctx, namespace, ref)
+}
+
+// ClientsetOwnerLookup fetches the owners of the built-in workload kinds using a clientset
+type ClientsetOwnerLookup struct {
+	Client kubernetes.Interface
+}
+
+var _ OwnerLookup = &ClientsetOwnerLookup{}
+
+// GetOwner implements OwnerLookup
+func (l *ClientsetOwnerLookup) GetOwner(ctx context.Context, namespace string, ref metav1.OwnerReference) (metav1.Object, error) {
+	opts := metav1.GetOptions{}
+	switch ref.Kind {
+	case "Pod":
+		return l.Client.CoreV1().Pods(namespace).Get(ctx, ref.Name, opts)
+	case "ReplicationController":
+		return l.Client.CoreV1().ReplicationControllers(namespace).Get(ctx, ref.Name, opts)
+	case "ReplicaSet":
+		return l.Client.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, opts)
+	case "Deployment":
+		return l.Client.AppsV1().Deployments(namespace).Get(ctx, ref.Name, opts)
+	case "StatefulSet":
+		return l.Client.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, opts)
+	case "DaemonSet":
+		return l.Client.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, opts)
+	case "Job":
+		return l.Client.BatchV1().Jobs(namespace).Get(ctx, ref.Name, opts)
+	case "CronJob":
+		return l.Client.BatchV1().CronJobs(namespace).Get(ctx, ref.Name, opts)
+	}
+	// unknown kinds (e.g. CRDs) are considered top-level workloads
+	gv, _ := schema.ParseGroupVersion(ref.APIVersion)
+	return nil, errors.NewNotFound(gv.WithResource(strings.ToLower(ref.Kind)).GroupResource(), ref.Name)
+}
+
+// OwnerResolver resolves the top-level workload of an object by walking its owner references,
+// e.g. Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob
+type OwnerResolver struct {
+	Lookup   OwnerLookup
+	MaxDepth int
+}
+
+// NewOwnerResolver returns an OwnerResolver using the given lookup
+func NewOwnerResolver(lookup OwnerLookup) *OwnerResolver {
+	return &OwnerResolver{Lookup: lookup, MaxDepth: DefaultMaxOwnerDepth}
+}
+
+// NewClientsetOwnerResolver returns an OwnerResolver fetching owners with the given clientset
+func NewClientsetOwnerResolver(client kubernetes.Interface) *OwnerResolver {
+	return NewOwnerResolver(&ClientsetOwnerLookup{Client: client})
+}
+
+// Resolve returns the WLID of the top-level owner of the object, or of the object itself if it has no owner.
+// When an owner cannot be found, the walk stops and the WLID of the last known owner reference is returned.
+func (r *OwnerResolver) Resolve(ctx context.Context, cluster string, obj runtime.Object) (WLID, error) {
+	w, err := FromObject(cluster, obj)
+	if err != nil {
+		return WLID{}, err
+	}
+	current, err := meta.Accessor(obj)
+	if err != nil {
+		return WLID{}, fmt.Errorf("failed to access object metadata: %w", err)
+	}
+
+	maxDepth := r.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxOwnerDepth
+	}
+	namespace := current.GetNamespace()
+	for depth := 0; ; depth++ {
+		ref := controllerRef(current.GetOwnerReferences())
+		// static pods are owned by their node, the pod is the workload
+		if ref == nil || ref.Kind == "Node" {
+			return w, nil
+		}
+		if depth >= maxDepth {
+			return w, fmt.Errorf("owner references of %s exceed max depth %d", w.String(), maxDepth)
+		}
+		w = NewK8sWLID(cluster, namespace, ref.Kind, ref.Name)
+		owner, err := r.Lookup.GetOwner(ctx, namespace, *ref)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return w, nil
+			}
+			return WLID{}, fmt.Errorf("failed to get owner %s: %w", w.String(), err)
+		}
+		current = owner
+	}
+}
+
+// controllerRef returns the controller owner reference, or the first owner reference if none is a controller
+func controllerRef(refs []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return &refs[i]
+		}
+	}
+	if len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
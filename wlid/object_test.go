@@ -0,0 +1,134 @@
+package wlid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func objectMeta(name, namespace string, owners ...metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, OwnerReferences: owners}
+}
+
+func ownerRef(apiVersion, kind, name string) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, Controller: ptr.To(true)}
+}
+
+func TestFromObject(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  runtime.Object
+		want string
+	}{
+		{
+			name: "typed object without TypeMeta",
+			obj:  &appsv1.Deployment{ObjectMeta: objectMeta("nginx", "default")},
+			want: "wlid://cluster-c/namespace-default/deployment-nginx",
+		},
+		{
+			name: "typed object with TypeMeta",
+			obj: &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+				ObjectMeta: objectMeta("nginx", "default"),
+			},
+			want: "wlid://cluster-c/namespace-default/pod-nginx",
+		},
+		{
+			name: "unstructured",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "Rollout",
+				"metadata":   map[string]interface{}{"name": "web", "namespace": "prod"},
+			}},
+			want: "wlid://cluster-c/namespace-prod/rollout-web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromObject("c", tt.obj)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestOwnerResolver(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: objectMeta("nginx", "default")},
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("nginx-5d78c9869d", "default", ownerRef("apps/v1", "Deployment", "nginx"))},
+		&batchv1.CronJob{ObjectMeta: objectMeta("backup", "default")},
+		&batchv1.Job{ObjectMeta: objectMeta("backup-28000000", "default", ownerRef("batch/v1", "CronJob", "backup"))},
+		&appsv1.ReplicaSet{ObjectMeta: objectMeta("orphan-5d78c9869d", "default", ownerRef("apps/v1", "Deployment", "deleted"))},
+	)
+	resolver := NewClientsetOwnerResolver(client)
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want string
+	}{
+		{
+			name: "pod -> replicaset -> deployment",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("nginx-5d78c9869d-abcde", "default", ownerRef("apps/v1", "ReplicaSet", "nginx-5d78c9869d"))},
+			want: "wlid://cluster-c/namespace-default/deployment-nginx",
+		},
+		{
+			name: "pod -> job -> cronjob",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("backup-28000000-abcde", "default", ownerRef("batch/v1", "Job", "backup-28000000"))},
+			want: "wlid://cluster-c/namespace-default/cronjob-backup",
+		},
+		{
+			name: "owner not found",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("orphan-5d78c9869d-abcde", "default", ownerRef("apps/v1", "ReplicaSet", "orphan-5d78c9869d"))},
+			want: "wlid://cluster-c/namespace-default/deployment-deleted",
+		},
+		{
+			name: "crd owner",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("web-0", "default", ownerRef("argoproj.io/v1alpha1", "Rollout", "web"))},
+			want: "wlid://cluster-c/namespace-default/rollout-web",
+		},
+		{
+			name: "static pod",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("etcd-node1", "kube-system", ownerRef("v1", "Node", "node1"))},
+			want: "wlid://cluster-c/namespace-kube-system/pod-etcd-node1",
+		},
+		{
+			name: "no owner",
+			pod:  &corev1.Pod{ObjectMeta: objectMeta("standalone", "default")},
+			want: "wlid://cluster-c/namespace-default/pod-standalone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), "c", tt.pod)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestOwnerResolverErrors(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: objectMeta("p", "default", ownerRef("apps/v1", "ReplicaSet", "rs"))}
+
+	failing := NewOwnerResolver(OwnerLookupFunc(func(_ context.Context, _ string, _ metav1.OwnerReference) (metav1.Object, error) {
+		return nil, fmt.Errorf("connection refused")
+	}))
+	_, err := failing.Resolve(context.Background(), "c", pod)
+	assert.Error(t, err)
+
+	cyclic := NewOwnerResolver(OwnerLookupFunc(func(_ context.Context, _ string, ref metav1.OwnerReference) (metav1.Object, error) {
+		return &appsv1.ReplicaSet{ObjectMeta: objectMeta(ref.Name, "default", ownerRef("apps/v1", "ReplicaSet", ref.Name))}, nil
+	}))
+	_, err = cyclic.Resolve(context.Background(), "c", pod)
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package wlid
+
+import (
+	"encoding"
+	"fmt"
+	"strings"
+)
+
+var (
+	_ encoding.TextMarshaler   = WLID{}
+	_ encoding.TextUnmarshaler = (*WLID)(nil)
+)
+
+// WLID is a parsed workload identifier.
+// The zero value is an empty identifier and is rendered as an empty string.
+type WLID struct {
+	// cluster/datacenter
+	Level0     string
+	Level0Type string
+
+	// namespace/project
+	Level1     string
+	Level1Type string
+
+	Kind string
+	Name string
+}
+
+// NewK8sWLID returns a k8s WLID (cluster/namespace), the namespace is empty for cluster-scoped resources
+func NewK8sWLID(cluster, namespace, kind, name string) WLID {
+	return WLID{
+		Level0:     cluster,
+		Level0Type: strings.TrimSuffix(ClusterWlidPrefix, "-"),
+		Level1:     namespace,
+		Level1Type: strings.TrimSuffix(NamespaceWlidPrefix, "-"),
+		Kind:       GetK8SKindFronList(kind),
+		Name:       name,
+	}
+}
+
+// NewNativeWLID returns a native WLID (datacenter/project)
+func NewNativeWLID(datacenter, project, kind, name string) WLID {
+	return WLID{
+		Level0:     datacenter,
+		Level0Type: strings.TrimSuffix(DataCenterWlidPrefix, "-"),
+		Level1:     project,
+		Level1Type: strings.TrimSuffix(ProjectWlidPrefix, "-"),
+		Kind:       GetK8SKindFronList(kind),
+		Name:       name,
+	}
+}
+
+// Parse parses a wlid string such as "wlid://cluster-c/namespace-ns/deployment-name",
+// or "wlid://cluster-c/clusterrole-name" for cluster-scoped resources.
+// Partial identifiers (without name, kind or namespace) are accepted, use Validate to make sure all levels are set.
+// The components of EncodingV1 wlids (e.g. "wlid+v1://cluster-gke%2Fprod/...") are decoded, legacy components are kept as is.
+func Parse(s string) (WLID, error) {
+	w := WLID{}
+	if s == "" {
+		return w, fmt.Errorf("in Parse, expecting valid wlid received empty string")
+	}
+	if StringHasWhitespace(s) {
+		return w, fmt.Errorf("wlid %s invalid. whitespace found", s)
+	}
+	rest, enc, ok := TrimPrefix(s, WlidPrefix)
+	if !ok {
+		return w, fmt.Errorf("wlid %s invalid. missing %s prefix", s, WlidPrefix)
+	}
+
+	levels := strings.Split(rest, "/")
+	if len(levels) > 3 {
+		return w, fmt.Errorf("wlid %s invalid. expecting up to 3 levels, found %d", s, len(levels))
+	}
+
+	var level1Prefix string
+	switch {
+	case strings.HasPrefix(levels[0], ClusterWlidPrefix):
+		w.Level0Type = strings.TrimSuffix(ClusterWlidPrefix, "-")
+		w.Level0 = enc.Unescape(levels[0][len(ClusterWlidPrefix):])
+		level1Prefix = NamespaceWlidPrefix
+	case strings.HasPrefix(levels[0], DataCenterWlidPrefix):
+		w.Level0Type = strings.TrimSuffix(DataCenterWlidPrefix, "-")
+		w.Level0 = enc.Unescape(levels[0][len(DataCenterWlidPrefix):])
+		level1Prefix = ProjectWlidPrefix
+	default:
+		return w, fmt.Errorf("wlid %s invalid. unknown level types", s)
+	}
+	w.Level1Type = strings.TrimSuffix(level1Prefix, "-")
+	levels = levels[1:]
+
+	if len(levels) > 0 && strings.HasPrefix(levels[0], level1Prefix) {
+		w.Level1 = enc.Unescape(levels[0][len(level1Prefix):])
+		levels = levels[1:]
+	} else if len(levels) > 1 {
+		return w, fmt.Errorf("wlid %s invalid. unknown level types", s)
+	}
+
+	// the remaining level is the kind, cluster-scoped resources have no level1
+	if len(levels) > 0 {
+		if levels[0] == "" {
+			return w, fmt.Errorf("wlid %s invalid. empty kind", s)
+		}
+		kind, name, _ := strings.Cut(levels[0], "-")
+		w.Kind = GetK8SKindFronList(enc.Unescape(kind))
+		w.Name = enc.Unescape(name)
+	}
+	return w, nil
+}
+
+// MustParse is like Parse but panics if the wlid cannot be parsed
+func MustParse(s string) WLID {
+	w, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// String returns the wlid string representation.
+// An empty namespace/project is kept for namespaced kinds, which Validate rejects.
+func (w WLID) String() string {
+	s, _ := w.format()
+	return s
+}
+
+func (w WLID) format() (string, error) {
+	if w.IsZero() {
+		return "", nil
+	}
+	return generateWLID(w.Level0Type+"-", w.Level0, w.Level1Type+"-", w.Level1, w.Kind, w.Name)
+}
+
+// IsZero returns true if the WLID is empty
+func (w WLID) IsZero() bool {
+	return w == WLID{}
+}
+
+// IsClusterScoped returns true if the WLID identifies a resource without namespace/project
+func (w WLID) IsClusterScoped() bool {
+	return w.Level1 == "" && w.Kind != ""
+}
+
+// IsK8s returns true if the WLID is a cluster/namespace identifier
+func (w WLID) IsK8s() bool {
+	return w.Level0Type+"-" == ClusterWlidPrefix
+}
+
+// Validate checks that all levels of the WLID are set, the namespace/project may be empty only for cluster-scoped kinds.
+// Reserved characters (e.g. whitespace or '/') are valid, they are escaped by String.
+func (w WLID) Validate() error {
+	switch {
+	case w.Level0Type+"-" == ClusterWlidPrefix && w.Level1Type+"-" == NamespaceWlidPrefix:
+	case w.Level0Type+"-" == DataCenterWlidPrefix && w.Level1Type+"-" == ProjectWlidPrefix:
+	default:
+		return fmt.Errorf("invalid WLID level types: %s/%s", w.Level0Type, w.Level1Type)
+	}
+	if w.Level0 == "" || w.Kind == "" || w.Name == "" {
+		return fmt.Errorf("one or more entities are empty, wlid: %s", w.String())
+	}
+	if w.Level1 == "" && DefaultKindRegistry.IsNamespaced(w.Kind) {
+		return fmt.Errorf("wlid %s invalid. empty %s for namespaced kind %s", w.String(), w.Level1Type, w.Kind)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler, an empty namespace/project of a namespaced kind is an error
+func (w WLID) MarshalText() ([]byte, error) {
+	s, err := w.format()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (w *WLID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = WLID{}
+		return nil
+	}
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
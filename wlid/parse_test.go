@@ -0,0 +1,146 @@
+package wlid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		wlid    string
+		want    WLID
+		wantErr bool
+	}{
+		{
+			name: "k8s wlid",
+			wlid: "wlid://cluster-HipsterShopCluster2/namespace-prod/deployment-cartservice",
+			want: NewK8sWLID("HipsterShopCluster2", "prod", "Deployment", "cartservice"),
+		},
+		{
+			name: "name with dashes",
+			wlid: "wlid://cluster-gke_armo-test-clusters_us-central1-c/namespace-kube-system/replicaset-coredns-5d78c9869d",
+			want: NewK8sWLID("gke_armo-test-clusters_us-central1-c", "kube-system", "ReplicaSet", "coredns-5d78c9869d"),
+		},
+		{
+			name: "name looking like a kind",
+			wlid: "wlid://cluster-c/namespace-ns/pod-pod",
+			want: NewK8sWLID("c", "ns", "Pod", "pod"),
+		},
+		{
+			name: "native wlid",
+			wlid: "wlid://datacenter-dc/project-default/dockerized-nginx",
+			want: NewNativeWLID("dc", "default", "dockerized", "nginx"),
+		},
+		{
+			name: "partial wlid",
+			wlid: "wlid://cluster-c/namespace-ns",
+			want: NewK8sWLID("c", "ns", "", ""),
+		},
+		{
+			name:    "empty",
+			wlid:    "",
+			wantErr: true,
+		},
+		{
+			name:    "missing prefix",
+			wlid:    "cluster-c/namespace-ns/deployment-name",
+			wantErr: true,
+		},
+		{
+			name:    "whitespace",
+			wlid:    "wlid://cluster-c/namespace-ns/deployment-my name",
+			wantErr: true,
+		},
+		{
+			name:    "unknown levels",
+			wlid:    "wlid://cluster-c/project-p/deployment-name",
+			wantErr: true,
+		},
+		{
+			name:    "too many levels",
+			wlid:    "wlid://cluster-c/namespace-ns/deployment-name/extra",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.wlid)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wlid, got.String())
+		})
+	}
+}
+
+func TestWLIDValidate(t *testing.T) {
+	assert.NoError(t, MustParse("wlid://cluster-c/namespace-ns/deployment-name").Validate())
+	assert.Error(t, MustParse("wlid://cluster-c/namespace-ns").Validate())
+	assert.Error(t, MustParse("wlid://cluster-c/namespace-/deployment-name").Validate())
+	assert.Error(t, WLID{}.Validate())
+}
+
+func TestWLIDJSON(t *testing.T) {
+	type event struct {
+		Wlid  WLID  `json:"wlid"`
+		Other *WLID `json:"other,omitempty"`
+	}
+	in := event{Wlid: NewK8sWLID("c", "ns", "StatefulSet", "db")}
+	b, err := json.Marshal(in)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"wlid":"wlid://cluster-c/namespace-ns/statefulset-db"}`, string(b))
+
+	out := event{}
+	assert.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, in, out)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"wlid":"not-a-wlid"}`), &out))
+}
+
+func TestParseClusterScoped(t *testing.T) {
+	w, err := Parse("wlid://cluster-c/clusterrole-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, NewK8sWLID("c", "", "ClusterRole", "admin"), w)
+	assert.True(t, w.IsClusterScoped())
+	assert.NoError(t, w.Validate())
+
+	// legacy form with an empty namespace
+	legacy, err := Parse("wlid://cluster-c/namespace-/clusterrole-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, w, legacy)
+	assert.Equal(t, "wlid://cluster-c/clusterrole-admin", legacy.String())
+
+	ns, err := Parse("wlid://cluster-c/namespace-/namespace-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, NewK8sWLID("c", "", "Namespace", "prod"), ns)
+	assert.Equal(t, "wlid://cluster-c/namespace-/namespace-prod", ns.String())
+
+	assert.Error(t, MustParse("wlid://cluster-c/deployment-nginx").Validate())
+	// the empty namespace of a namespaced kind is kept
+	deployment := NewK8sWLID("c", "", "Deployment", "nginx")
+	assert.Equal(t, "wlid://cluster-c/namespace-/deployment-nginx", deployment.String())
+	_, err = deployment.MarshalText()
+	assert.Error(t, err)
+
+	// unregistered kinds without namespace, e.g. cluster-scoped CRDs, round trip
+	widget, err := Parse("wlid://cluster-c/widget-w")
+	assert.NoError(t, err)
+	assert.NoError(t, widget.Validate())
+	assert.Equal(t, "wlid://cluster-c/widget-w", widget.String())
+	b, err := json.Marshal(widget)
+	assert.NoError(t, err)
+	var unmarshaled WLID
+	assert.NoError(t, json.Unmarshal(b, &unmarshaled))
+	assert.Equal(t, widget, unmarshaled)
+	assert.NoError(t, NewK8sWLID("c", "", "Widget", "w").Validate())
+	_, err = NewK8sWLID("c", "", "Widget", "w").MarshalText()
+	assert.NoError(t, err)
+	_, err = Parse("wlid://cluster-c/clusterrole-admin/extra")
+	assert.Error(t, err)
+}
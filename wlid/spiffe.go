@@ -0,0 +1,207 @@
+package wlid
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// SPIFFEScheme is the scheme of SPIFFE IDs
+	SPIFFEScheme = "spiffe://"
+
+	// DefaultSPIFFEPathTemplate is the path of the SPIFFE IDs issued by SPIRE k8s workload registrar and Istio
+	DefaultSPIFFEPathTemplate = "/ns/{namespace}/sa/{serviceaccount}"
+)
+
+// SPIFFE path template placeholders
+const (
+	SPIFFEClusterPlaceholder        = "{cluster}"
+	SPIFFENamespacePlaceholder      = "{namespace}"
+	SPIFFEServiceAccountPlaceholder = "{serviceaccount}"
+	SPIFFEKindPlaceholder           = "{kind}"
+	SPIFFENamePlaceholder           = "{name}"
+)
+
+var spiffePlaceholders = []string{SPIFFEClusterPlaceholder, SPIFFENamespacePlaceholder, SPIFFEServiceAccountPlaceholder,
+	SPIFFEKindPlaceholder, SPIFFENamePlaceholder}
+
+// SPIFFEIdentity is a workload identity carried by a SPIFFE ID
+type SPIFFEIdentity struct {
+	TrustDomain    string
+	WLID           WLID
+	ServiceAccount string
+}
+
+// SPIFFEConverter converts WLIDs to and from SPIFFE IDs "spiffe://<trust-domain><path>".
+//
+// The path is built from PathTemplate, where every segment may hold a single placeholder with an optional
+// literal prefix and suffix, e.g. "/ns/{namespace}/sa/{serviceaccount}/kind-{kind}/{name}".
+// When the template has no {cluster} placeholder, the cluster of the parsed WLIDs is Cluster.
+type SPIFFEConverter struct {
+	TrustDomain  string
+	PathTemplate string
+	Cluster      string
+}
+
+// NewSPIFFEConverter returns a converter using DefaultSPIFFEPathTemplate
+func NewSPIFFEConverter(trustDomain, cluster string) *SPIFFEConverter {
+	return &SPIFFEConverter{TrustDomain: trustDomain, PathTemplate: DefaultSPIFFEPathTemplate, Cluster: cluster}
+}
+
+type spiffeSegment struct {
+	prefix      string
+	placeholder string // empty for literal segments
+	suffix      string
+}
+
+func (c *SPIFFEConverter) segments() ([]spiffeSegment, error) {
+	tmpl := c.PathTemplate
+	if tmpl == "" {
+		tmpl = DefaultSPIFFEPathTemplate
+	}
+	if !strings.HasPrefix(tmpl, "/") {
+		return nil, fmt.Errorf("invalid SPIFFE path template %s, expecting a leading /", tmpl)
+	}
+	parts := strings.Split(tmpl[1:], "/")
+	segments := make([]spiffeSegment, 0, len(parts))
+	for _, part := range parts {
+		segment := spiffeSegment{prefix: part}
+		for _, placeholder := range spiffePlaceholders {
+			if before, after, found := strings.Cut(part, placeholder); found {
+				if strings.Contains(before+after, "{") {
+					return nil, fmt.Errorf("invalid SPIFFE path template %s, one placeholder per segment is supported", tmpl)
+				}
+				segment = spiffeSegment{prefix: before, placeholder: placeholder, suffix: after}
+				break
+			}
+		}
+		if segment.placeholder == "" && strings.Contains(part, "{") {
+			return nil, fmt.Errorf("invalid SPIFFE path template %s, unknown placeholder in %s", tmpl, part)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// ToSPIFFE returns the SPIFFE ID of the workload running with the given service account
+func (c *SPIFFEConverter) ToSPIFFE(w WLID, serviceAccount string) (string, error) {
+	if err := validateTrustDomain(c.TrustDomain); err != nil {
+		return "", err
+	}
+	segments, err := c.segments()
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{
+		SPIFFEClusterPlaceholder:        w.Level0,
+		SPIFFENamespacePlaceholder:      w.Level1,
+		SPIFFEServiceAccountPlaceholder: serviceAccount,
+		SPIFFEKindPlaceholder:           normalizeKind(w.Kind),
+		SPIFFENamePlaceholder:           w.Name,
+	}
+
+	var id strings.Builder
+	id.WriteString(SPIFFEScheme)
+	id.WriteString(c.TrustDomain)
+	for _, segment := range segments {
+		value := values[segment.placeholder]
+		if segment.placeholder != "" && value == "" {
+			return "", fmt.Errorf("cannot convert %s to SPIFFE ID, empty %s", w.String(), segment.placeholder)
+		}
+		id.WriteString("/")
+		id.WriteString(segment.prefix)
+		id.WriteString(value)
+		id.WriteString(segment.suffix)
+	}
+	if err := validateSPIFFEPath(id.String()[len(SPIFFEScheme)+len(c.TrustDomain):]); err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// FromSPIFFE parses a SPIFFE ID issued with the converter trust domain and path template.
+// The returned WLID has no kind and name if the template has no {kind} and {name} placeholders.
+func (c *SPIFFEConverter) FromSPIFFE(id string) (SPIFFEIdentity, error) {
+	identity := SPIFFEIdentity{}
+	if !strings.HasPrefix(id, SPIFFEScheme) {
+		return identity, fmt.Errorf("invalid SPIFFE ID %s, missing %s scheme", id, SPIFFEScheme)
+	}
+	trustDomain, path, _ := strings.Cut(id[len(SPIFFEScheme):], "/")
+	if err := validateTrustDomain(trustDomain); err != nil {
+		return identity, err
+	}
+	if c.TrustDomain != "" && trustDomain != c.TrustDomain {
+		return identity, fmt.Errorf("invalid SPIFFE ID %s, expecting trust domain %s", id, c.TrustDomain)
+	}
+	if err := validateSPIFFEPath("/" + path); err != nil {
+		return identity, err
+	}
+	segments, err := c.segments()
+	if err != nil {
+		return identity, err
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != len(segments) {
+		return identity, fmt.Errorf("SPIFFE ID %s does not match template %s", id, c.PathTemplate)
+	}
+
+	values := map[string]string{SPIFFEClusterPlaceholder: c.Cluster}
+	for i, segment := range segments {
+		part := parts[i]
+		if !strings.HasPrefix(part, segment.prefix) || !strings.HasSuffix(part[len(segment.prefix):], segment.suffix) {
+			return identity, fmt.Errorf("SPIFFE ID %s does not match template %s", id, c.PathTemplate)
+		}
+		value := part[len(segment.prefix) : len(part)-len(segment.suffix)]
+		if segment.placeholder == "" {
+			if value != "" {
+				return identity, fmt.Errorf("SPIFFE ID %s does not match template %s", id, c.PathTemplate)
+			}
+			continue
+		}
+		if value == "" {
+			return identity, fmt.Errorf("SPIFFE ID %s invalid, empty %s", id, segment.placeholder)
+		}
+		values[segment.placeholder] = value
+	}
+
+	identity.TrustDomain = trustDomain
+	identity.ServiceAccount = values[SPIFFEServiceAccountPlaceholder]
+	identity.WLID = NewK8sWLID(values[SPIFFEClusterPlaceholder], values[SPIFFENamespacePlaceholder], values[SPIFFEKindPlaceholder], values[SPIFFENamePlaceholder])
+	return identity, nil
+}
+
+// IsSPIFFEID returns true if the id has the SPIFFE scheme
+func IsSPIFFEID(id string) bool {
+	return strings.HasPrefix(id, SPIFFEScheme)
+}
+
+// validateTrustDomain checks the trust domain charset defined by the SPIFFE ID specification
+func validateTrustDomain(trustDomain string) error {
+	if trustDomain == "" {
+		return fmt.Errorf("empty SPIFFE trust domain")
+	}
+	for _, c := range trustDomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return fmt.Errorf("invalid SPIFFE trust domain %s, invalid character %q", trustDomain, c)
+		}
+	}
+	return nil
+}
+
+// validateSPIFFEPath checks the path segments charset defined by the SPIFFE ID specification
+func validateSPIFFEPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(path[1:], "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid SPIFFE path %s, invalid segment %q", path, segment)
+		}
+		for _, c := range segment {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+				return fmt.Errorf("invalid SPIFFE path %s, invalid character %q", path, c)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package wlid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSPIFFEConverter(t *testing.T) {
+	tests := []struct {
+		name           string
+		converter      *SPIFFEConverter
+		wlid           WLID
+		serviceAccount string
+		want           string
+		wantWlid       WLID
+	}{
+		{
+			name:           "default template",
+			converter:      NewSPIFFEConverter("cluster.local", "c"),
+			wlid:           NewK8sWLID("c", "prod", "Deployment", "cartservice"),
+			serviceAccount: "cart",
+			want:           "spiffe://cluster.local/ns/prod/sa/cart",
+			wantWlid:       NewK8sWLID("c", "prod", "", ""),
+		},
+		{
+			name: "workload template",
+			converter: &SPIFFEConverter{
+				TrustDomain:  "example.org",
+				PathTemplate: "/cluster/{cluster}/ns/{namespace}/sa/{serviceaccount}/{kind}/{name}",
+			},
+			wlid:           NewK8sWLID("c1", "prod", "StatefulSet", "db-main"),
+			serviceAccount: "default",
+			want:           "spiffe://example.org/cluster/c1/ns/prod/sa/default/statefulset/db-main",
+			wantWlid:       NewK8sWLID("c1", "prod", "StatefulSet", "db-main"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.converter.ToSPIFFE(tt.wlid, tt.serviceAccount)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, IsSPIFFEID(got))
+
+			identity, err := tt.converter.FromSPIFFE(got)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.serviceAccount, identity.ServiceAccount)
+			assert.Equal(t, tt.converter.TrustDomain, identity.TrustDomain)
+			assert.Equal(t, tt.wantWlid, identity.WLID)
+		})
+	}
+}
+
+func TestSPIFFEConverterErrors(t *testing.T) {
+	c := NewSPIFFEConverter("cluster.local", "c")
+	_, err := c.ToSPIFFE(NewK8sWLID("c", "prod", "Deployment", "cartservice"), "")
+	assert.Error(t, err)
+
+	for _, id := range []string{
+		"wlid://cluster-c/namespace-prod/deployment-cartservice",
+		"spiffe://other.domain/ns/prod/sa/cart",
+		"spiffe://Cluster.Local/ns/prod/sa/cart",
+		"spiffe://cluster.local/ns/prod/sa/cart/extra",
+		"spiffe://cluster.local/namespace/prod/sa/cart",
+		"spiffe://cluster.local/ns//sa/cart",
+	} {
+		_, err := c.FromSPIFFE(id)
+		assert.Error(t, err, id)
+	}
+
+	bad := &SPIFFEConverter{TrustDomain: "cluster.local", PathTemplate: "/ns/{namespace}{name}"}
+	_, err = bad.ToSPIFFE(NewK8sWLID("c", "prod", "Deployment", "cartservice"), "cart")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package wlid
 import (
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 // API fields
@@ -21,129 +22,152 @@ var (
 	ProjectWlidPrefix    = "project-"
 	SecretSIDPrefix      = "secret-"
 	SubSecretSIDPrefix   = "subsecret-"
-	K8SKindsList         = []string{"ComponentStatus", "ConfigMap", "ControllerRevision", "CronJob",
+	// K8SKindsList is the static seed of the DefaultKindRegistry, kinds registered at runtime are not added to it
+	K8SKindsList = []string{"ComponentStatus", "ConfigMap", "ControllerRevision", "CronJob",
 		"CustomResourceDefinition", "DaemonSet", "Deployment", "Endpoints", "Event", "HorizontalPodAutoscaler",
 		"Ingress", "Job", "Lease", "LimitRange", "LocalSubjectAccessReview", "MutatingWebhookConfiguration",
 		"Namespace", "NetworkPolicy", "Node", "PersistentVolume", "PersistentVolumeClaim", "Pod",
 		"PodDisruptionBudget", "PodSecurityPolicy", "PodTemplate", "PriorityClass", "ReplicaSet",
 		"ReplicationController", "ResourceQuota", "Role", "RoleBinding", "Secret", "SelfSubjectAccessReview",
 		"SelfSubjectRulesReview", "Service", "ServiceAccount", "StatefulSet", "StorageClass",
-		"SubjectAccessReview", "TokenReview", "ValidatingWebhookConfiguration", "VolumeAttachment"}
+		"SubjectAccessReview", "TokenReview", "ValidatingWebhookConfiguration", "VolumeAttachment",
+		"ClusterRole", "ClusterRoleBinding"}
 	NativeKindsList = []string{"Dockerized", "Native"}
-	KindReverseMap  = map[string]string{}
-	dataImagesList  = []string{}
+	// Deprecated: KindReverseMap is a static snapshot of the seeded kinds, use DefaultKindRegistry.ReverseMap
+	KindReverseMap = map[string]string{}
+	dataImagesList = []string{}
 )
 
-// represents workload basic info
+// represents workload basic info.
+// Level1 and Level1Type were tagged "level0" and "level0Type" before, the colliding tags made encoding/json drop
+// all four level fields, so the previous encoding carries only the kind and the name and decodes the same way.
 type SpiffeBasicInfo struct {
 	//cluster/datacenter
 	Level0     string `json:"level0"`
 	Level0Type string `json:"level0Type"`
 
 	//namespace/project
-	Level1     string `json:"level0"`
-	Level1Type string `json:"level0Type"`
+	Level1     string `json:"level1"`
+	Level1Type string `json:"level1Type"`
 
 	Kind string `json:"kind"`
 	Name string `json:"name"`
 }
 
 func IsWlid(id string) bool {
-	return strings.HasPrefix(id, WlidPrefix)
+	_, _, ok := TrimPrefix(id, WlidPrefix)
+	return ok
 }
 
 func IsSid(id string) bool {
-	return strings.HasPrefix(id, SidPrefix)
+	_, _, ok := TrimPrefix(id, SidPrefix)
+	return ok
 }
 
 // GetK8SKindFronList get the calculated wlid
 func GetK8SKindFronList(kind string) string { // TODO GetK8SKindFromList
-	for i := range K8SKindsList {
-		if strings.ToLower(kind) == strings.ToLower(K8SKindsList[i]) {
-			return K8SKindsList[i]
-		}
+	if k, ok := DefaultKindRegistry.Lookup(kind); ok {
+		return k
 	}
 	return kind
 }
 
 // IsK8SKindInList Check if the kind is a known kind
 func IsK8SKindInList(kind string) bool {
-	for i := range K8SKindsList {
-		if strings.ToLower(kind) == strings.ToLower(K8SKindsList[i]) {
-			return true
-		}
-	}
-	return false
+	return DefaultKindRegistry.IsK8s(kind)
 }
 
 // generateWLID
-func generateWLID(pLevel0, level0, pLevel1, level1, k, name string) string {
-	kind := strings.ToLower(k)
-	kind = strings.Replace(kind, "-", "", -1)
+// components are written as is, unless one of them cannot be (see ComponentsEncoding), then the wlid is EncodingV1:
+// its components are escaped, kinds are lowercased and their dashes escaped.
+// cluster-scoped resources (empty level1) have no level1, e.g. "wlid://cluster-c/clusterrole-admin",
+// unless their kind collides with the level1 prefix (e.g. Namespace), which keeps an empty level1: "wlid://cluster-c/namespace-/namespace-default".
+// An empty level1 of a registered namespaced kind is an error, the wlid keeps the empty level1: "wlid://cluster-c/namespace-/deployment-nginx".
+// Unregistered kinds (e.g. CRD kinds) are cluster-scoped when level1 is empty, as in Validate.
+func generateWLID(pLevel0, level0, pLevel1, level1, k, name string) (string, error) {
+	return encodeWLID(wlidEncoding(level0, level1, k, name), pLevel0, level0, pLevel1, level1, k, name)
+}
+
+// wlidEncoding returns the encoding of a wlid of the given components, a dash in the kind requires EncodingV1
+func wlidEncoding(level0, level1, k, name string, components ...string) Encoding {
+	if strings.Contains(k, "-") {
+		return EncodingV1
+	}
+	return ComponentsEncoding(append(components, level0, level1, k, name)...)
+}
+
+func encodeWLID(enc Encoding, pLevel0, level0, pLevel1, level1, k, name string) (string, error) {
+	kind := enc.escapeKind(k)
+
+	var err error
+	if level1 == "" && DefaultKindRegistry.IsNamespaced(k) {
+		err = fmt.Errorf("empty %s for namespaced kind %s", strings.TrimSuffix(pLevel1, "-"), k)
+	}
 
 	var wlid strings.Builder
-	wlid.WriteString(WlidPrefix)
+	wlid.WriteString(enc.Prefix(WlidPrefix))
 	wlid.WriteString(pLevel0)
-	wlid.WriteString(level0)
+	wlid.WriteString(enc.Escape(level0))
 
-	// don't exit if level1 is empty
-	wlid.WriteString("/")
-	wlid.WriteString(pLevel1)
-	wlid.WriteString(level1)
+	if level1 != "" || err != nil || kind+"-" == pLevel1 {
+		wlid.WriteString("/")
+		wlid.WriteString(pLevel1)
+		wlid.WriteString(enc.Escape(level1))
+	}
 
 	if kind == "" {
-		return wlid.String()
+		return wlid.String(), err
 	}
 	wlid.WriteString("/")
 	wlid.WriteString(kind)
 
 	if name == "" {
-		return wlid.String()
+		return wlid.String(), err
 	}
 	wlid.WriteString("-")
-	wlid.WriteString(name)
+	wlid.WriteString(enc.Escape(name))
 
-	return wlid.String()
+	return wlid.String(), err
 }
 
 // GetWLID get the calculated wlid
 func GetWLID(level0, level1, k, name string) string {
-	return generateWLID(ClusterWlidPrefix, level0, NamespaceWlidPrefix, level1, k, name)
+	return GetK8sWLID(level0, level1, k, name)
 }
 
-// GetK8sWLID get the k8s calculated wlid
+// GetK8sWLID get the k8s calculated wlid.
+// An empty namespace is kept for namespaced kinds, use NewK8sWLID and Validate to check the namespace.
 func GetK8sWLID(level0, level1, k, name string) string {
-	return generateWLID(ClusterWlidPrefix, level0, NamespaceWlidPrefix, level1, k, name)
+	w, _ := generateWLID(ClusterWlidPrefix, level0, NamespaceWlidPrefix, level1, k, name)
+	return w
 }
 
 // GetNativeWLID get the native calculated wlid
 func GetNativeWLID(level0, level1, k, name string) string {
-	return generateWLID(DataCenterWlidPrefix, level0, ProjectWlidPrefix, level1, k, name)
+	w, _ := generateWLID(DataCenterWlidPrefix, level0, ProjectWlidPrefix, level1, k, name)
+	return w
 }
 
 // WildWlidContainsWlid does WildWlid contains Wlid
-func WildWlidContainsWlid(wildWlid, wlid string) bool { // TODO- test
+func WildWlidContainsWlid(wildWlid, wlid string) bool {
 	if wildWlid == wlid {
 		return true
 	}
-	wildWlidR, _ := RestoreMicroserviceIDsFromSpiffe(wildWlid)
-	wlidR, _ := RestoreMicroserviceIDsFromSpiffe(wlid)
-	if len(wildWlidR) > len(wildWlidR) {
-		// invalid wlid
+	w, err := CompileWildWLID(wildWlid)
+	if err != nil {
 		return false
 	}
-
-	for i := range wildWlidR {
-		if wildWlidR[i] != wlidR[i] {
-			return false
-		}
-	}
-	return true
+	return w.MatchString(wlid)
 }
 
-func restoreInnerIdentifiersFromID(spiffeSlices []string) []string {
+// restoreInnerIdentifiersFromID splits the levels and unescapes the EncodingV1 levels, legacy levels are returned as is
+func restoreInnerIdentifiersFromID(spiffeSlices []string, enc Encoding) []string {
 	if len(spiffeSlices) >= 1 && strings.HasPrefix(spiffeSlices[0], ClusterWlidPrefix) {
 		spiffeSlices[0] = spiffeSlices[0][len(ClusterWlidPrefix):]
+		// cluster-scoped resources have no namespace level
+		if len(spiffeSlices) == 2 && !strings.HasPrefix(spiffeSlices[1], NamespaceWlidPrefix) {
+			spiffeSlices = []string{spiffeSlices[0], NamespaceWlidPrefix, spiffeSlices[1]}
+		}
 	}
 	if len(spiffeSlices) >= 2 && strings.HasPrefix(spiffeSlices[1], NamespaceWlidPrefix) {
 		spiffeSlices[1] = spiffeSlices[1][len(NamespaceWlidPrefix):]
@@ -152,13 +176,27 @@ func restoreInnerIdentifiersFromID(spiffeSlices []string) []string {
 		dashIdx := strings.Index(spiffeSlices[2], "-")
 		spiffeSlices = append(spiffeSlices, spiffeSlices[2][dashIdx+1:])
 		spiffeSlices[2] = spiffeSlices[2][:dashIdx]
-		if val, ok := KindReverseMap[spiffeSlices[2]]; ok {
+	}
+	for i := range spiffeSlices {
+		spiffeSlices[i] = enc.Unescape(spiffeSlices[i])
+	}
+	if len(spiffeSlices) >= 3 {
+		if val, ok := DefaultKindRegistry.Lookup(spiffeSlices[2]); ok {
 			spiffeSlices[2] = val
 		}
 	}
 	return spiffeSlices
 }
 
+// trimSpiffePrefix removes the wlid or sid prefix and returns the encoding of the identifier
+func trimSpiffePrefix(spiffe string) (string, Encoding) {
+	if rest, enc, ok := TrimPrefix(spiffe, WlidPrefix); ok {
+		return rest, enc
+	}
+	rest, enc, _ := TrimPrefix(spiffe, SidPrefix)
+	return rest, enc
+}
+
 // RestoreMicroserviceIDsFromSpiffe -
 func RestoreMicroserviceIDsFromSpiffe(spiffe string) ([]string, error) {
 	if spiffe == "" {
@@ -169,21 +207,21 @@ func RestoreMicroserviceIDsFromSpiffe(spiffe string) ([]string, error) {
 		return nil, fmt.Errorf("wlid %s invalid. whitespace found", spiffe)
 	}
 
-	if strings.HasPrefix(spiffe, WlidPrefix) {
-		spiffe = spiffe[len(WlidPrefix):]
-	} else if strings.HasPrefix(spiffe, SidPrefix) {
-		spiffe = spiffe[len(SidPrefix):]
-	}
+	spiffe, enc := trimSpiffePrefix(spiffe)
 	spiffeSlices := strings.Split(spiffe, "/")
 	// The documented WLID format (https://cyberarmorio.sharepoint.com/sites/development2/Shared%20Documents/kubernetes_design1.docx?web=1)
 	if len(spiffeSlices) <= 3 {
-		spiffeSlices = restoreInnerIdentifiersFromID(spiffeSlices)
+		spiffeSlices = restoreInnerIdentifiersFromID(spiffeSlices, enc)
 	}
 	if len(spiffeSlices) != 4 { // first used WLID, deprecated since 24.10.2019
 		return spiffeSlices, fmt.Errorf("invalid WLID format. format received: %v", spiffeSlices)
 	}
 
 	for i := range spiffeSlices {
+		// cluster-scoped resources have an empty namespace
+		if i == 1 && spiffeSlices[i] == "" && !DefaultKindRegistry.IsNamespaced(spiffeSlices[2]) {
+			continue
+		}
 		if spiffeSlices[i] == "" {
 			return spiffeSlices, fmt.Errorf("one or more entities are empty, spiffeSlices: %v", spiffeSlices)
 		}
@@ -202,14 +240,10 @@ func RestoreMicroserviceIDs(spiffe string) []string {
 		return []string{}
 	}
 
-	if strings.HasPrefix(spiffe, WlidPrefix) {
-		spiffe = spiffe[len(WlidPrefix):]
-	} else if strings.HasPrefix(spiffe, SidPrefix) {
-		spiffe = spiffe[len(SidPrefix):]
-	}
+	spiffe, enc := trimSpiffePrefix(spiffe)
 	spiffeSlices := strings.Split(spiffe, "/")
 
-	return restoreInnerIdentifiersFromID(spiffeSlices)
+	return restoreInnerIdentifiersFromID(spiffeSlices, enc)
 }
 
 // GetClusterFromWlid parse wlid and get cluster
@@ -243,7 +277,7 @@ func GetKindFromWlid(wlid string) string {
 func GetNameFromWlid(wlid string) string {
 	r := RestoreMicroserviceIDs(wlid)
 	if len(r) >= 4 {
-		return GetK8SKindFronList(r[3])
+		return r[3]
 	}
 	return ""
 }
@@ -254,12 +288,9 @@ func IsWlidValid(wlid string) error {
 	return err
 }
 
-// StringHasWhitespace check if a string has whitespace
+// StringHasWhitespace check if a string has whitespace or control characters (e.g. tabs, newlines)
 func StringHasWhitespace(str string) bool {
-	if whitespace := strings.Index(str, " "); whitespace != -1 {
-		return true
-	}
-	return false
+	return strings.IndexFunc(str, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) != -1
 }
 
 func SpiffeToSpiffeInfo(spiffe string) (*SpiffeBasicInfo, error) {
@@ -283,12 +314,13 @@ func SpiffeToSpiffeInfo(spiffe string) (*SpiffeBasicInfo, error) {
 	if p0 == -1 || p1 == -1 || p2 == -1 {
 		return nil, fmt.Errorf("invalid spiffe %s", spiffe)
 	}
+	enc := DetectEncoding(spiffe)
 	basicInfo.Level0Type = splits[0][:p0]
-	basicInfo.Level0 = splits[0][p0+1:]
+	basicInfo.Level0 = enc.Unescape(splits[0][p0+1:])
 	basicInfo.Level1Type = splits[1][:p1]
-	basicInfo.Level1 = splits[1][p1+1:]
-	basicInfo.Kind = splits[2][:p2]
-	basicInfo.Name = splits[2][p2+1:]
+	basicInfo.Level1 = enc.Unescape(splits[1][p1+1:])
+	basicInfo.Kind = enc.Unescape(splits[2][:p2])
+	basicInfo.Name = enc.Unescape(splits[2][p2+1:])
 
 	return basicInfo, nil
 }
@@ -1,8 +1,10 @@
 package wlid
 
 import (
-	"github.com/stretchr/testify/assert"
+	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // tests wlid parse
@@ -39,9 +41,10 @@ func Test_generateWLID(t *testing.T) {
 		name    string
 	}
 	tests := []struct {
-		name string
-		args args
-		want string
+		name    string
+		args    args
+		want    string
+		wantErr bool
 	}{
 		{
 			name: "k8s wlid",
@@ -65,14 +68,108 @@ func Test_generateWLID(t *testing.T) {
 				k:       "ClusterRoleBinding",
 				name:    "cartservice",
 			},
-			want: "wlid://cluster-HipsterShopCluster2/namespace-/clusterrolebinding-cartservice",
-			// FIXME: do we want "wlid://cluster-HipsterShopCluster2/clusterrolebinding-cartservice" instead?
+			want: "wlid://cluster-HipsterShopCluster2/clusterrolebinding-cartservice",
+		},
+		{
+			name: "k8s wlid namespace kind",
+			args: args{
+				pLevel0: ClusterWlidPrefix,
+				level0:  "HipsterShopCluster2",
+				pLevel1: NamespaceWlidPrefix,
+				level1:  "",
+				k:       "Namespace",
+				name:    "prod",
+			},
+			want: "wlid://cluster-HipsterShopCluster2/namespace-/namespace-prod",
+		},
+		{
+			name: "k8s wlid cluster only",
+			args: args{
+				pLevel0: ClusterWlidPrefix,
+				level0:  "HipsterShopCluster2",
+				pLevel1: NamespaceWlidPrefix,
+			},
+			want: "wlid://cluster-HipsterShopCluster2",
+		},
+		{
+			name: "k8s wlid namespaced kind without namespace",
+			args: args{
+				pLevel0: ClusterWlidPrefix,
+				level0:  "HipsterShopCluster2",
+				pLevel1: NamespaceWlidPrefix,
+				k:       "Deployment",
+				name:    "cartservice",
+			},
+			want:    "wlid://cluster-HipsterShopCluster2/namespace-/deployment-cartservice",
+			wantErr: true,
+		},
+		{
+			name: "k8s wlid unregistered kind without namespace",
+			args: args{
+				pLevel0: ClusterWlidPrefix,
+				level0:  "HipsterShopCluster2",
+				pLevel1: NamespaceWlidPrefix,
+				k:       "Widget",
+				name:    "w",
+			},
+			want: "wlid://cluster-HipsterShopCluster2/widget-w",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := generateWLID(tt.args.pLevel0, tt.args.level0, tt.args.pLevel1, tt.args.level1, tt.args.k, tt.args.name)
+			got, err := generateWLID(tt.args.pLevel0, tt.args.level0, tt.args.pLevel1, tt.args.level1, tt.args.k, tt.args.name)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetNameFromWlid(t *testing.T) {
+	assert.Equal(t, "pod", GetNameFromWlid("wlid://cluster-c/namespace-ns/pod-pod"))
+	assert.Equal(t, "Pod", GetKindFromWlid("wlid://cluster-c/namespace-ns/pod-pod"))
+}
+
+func TestRestoreMicroserviceIDsFromSpiffeClusterScoped(t *testing.T) {
+	tests := []struct {
+		wlid    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			wlid: "wlid://cluster-c/clusterrole-admin",
+			want: []string{"c", "", "ClusterRole", "admin"},
+		},
+		{
+			wlid: "wlid://cluster-c/namespace-/validatingwebhookconfiguration-hook",
+			want: []string{"c", "", "ValidatingWebhookConfiguration", "hook"},
+		},
+		{
+			wlid: "wlid://cluster-c/namespace-/namespace-kube-system",
+			want: []string{"c", "", "Namespace", "kube-system"},
+		},
+		{
+			wlid:    "wlid://cluster-c/namespace-/deployment-nginx",
+			want:    []string{"c", "", "Deployment", "nginx"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wlid, func(t *testing.T) {
+			got, err := RestoreMicroserviceIDsFromSpiffe(tt.wlid)
+			assert.Equal(t, tt.wantErr, err != nil)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestSpiffeBasicInfoJSON(t *testing.T) {
+	info := SpiffeBasicInfo{Level0: "c", Level0Type: "cluster", Level1: "ns", Level1Type: "namespace", Kind: "deployment", Name: "web"}
+	b, err := json.Marshal(info)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"level0":"c","level0Type":"cluster","level1":"ns","level1Type":"namespace","kind":"deployment","name":"web"}`, string(b))
+
+	// the previous tags of Level1 collided with Level0, encoding/json dropped the level fields
+	var decoded SpiffeBasicInfo
+	assert.NoError(t, json.Unmarshal([]byte(`{"kind":"deployment","name":"web"}`), &decoded))
+	assert.Equal(t, SpiffeBasicInfo{Kind: "deployment", Name: "web"}, decoded)
+}